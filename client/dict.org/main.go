@@ -1,31 +1,70 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/dictproto"
 )
 
 func main() {
+	addr := flag.String("addr", "dict.dict.org:2628", "address of DICT server")
+	databases := flag.String("db", "!", "comma separated databases searched in order, * means all and ! the first having the word")
+	limit := flag.Int("limit", dictionary.DefaultDICTLimit, "max definitions from each database, 0 means no limit")
+	listDatabases := flag.Bool("databases", false, "list databases of server")
+	listStrategies := flag.Bool("strategies", false, "list match strategies of server")
+	strategy := flag.String("match", "", "match word by strategy like prefix, soundex or lev instead of defining it")
+	flag.Parse()
 
 	logger := log.New()
-	dict, err := dictionary.NewDICTClient(logger, "tcp", "dict.dict.org:2628", "!")
-	// dict, err := dictionary.NewMyPreferDictionary(logger)
+	dict, err := dictionary.NewDICTClient(logger, "tcp", *addr, strings.Split(*databases, ",")...)
 	if err != nil {
 		logger.Errorln("Fail to init dictionary:", err)
 		return
 	}
+	defer dict.Close()
+	dict.Limit = *limit
 	logger.SetLevel(logrus.InfoLevel)
+	ctx := context.Background()
 
-	argsWithoutProg := os.Args[1:]
+	if *listDatabases || *listStrategies {
+		var items []dictproto.Item
+		if *listDatabases {
+			items, err = dict.ShowDatabases(ctx)
+		} else {
+			items, err = dict.ShowStrategies(ctx)
+		}
+		if err != nil {
+			logger.Errorln("Show error:", err)
+			return
+		}
+		for _, item := range items {
+			fmt.Printf("%s\t%s\n", item.Name, item.Description)
+		}
+		return
+	}
 
-	searchWord := strings.Join(argsWithoutProg, " ")
-	results, err := dict.Search(searchWord)
+	searchWord := strings.Join(flag.Args(), " ")
+	if len(*strategy) != 0 {
+		for _, db := range dict.Databases {
+			matches, err := dict.MatchDatabase(ctx, db, *strategy, searchWord)
+			if err != nil {
+				logger.Errorln("Match error:", err)
+				return
+			}
+			for _, match := range matches {
+				fmt.Printf("%s\t%s\n", match.Database, match.Word)
+			}
+		}
+		return
+	}
+	results, err := dict.Search(ctx, searchWord)
 	if err == dictionary.ErrorNoDef {
 		fmt.Printf("no definition for: %s\n", searchWord)
 		return
@@ -34,5 +73,7 @@ func main() {
 		return
 	}
 
-	fmt.Printf("definition: %s\n", results)
+	for _, result := range results {
+		fmt.Printf("[%s]\n%s\n\n", result.Source, result.Text)
+	}
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -25,10 +27,13 @@ func main() {
 	argsWithoutProg := os.Args[1:]
 
 	searchWord := strings.Join(argsWithoutProg, " ")
-	results, err := dict.Search(searchWord)
+	results, err := dict.Search(context.Background(), searchWord)
+	var partial *dictionary.PartialError
 	if err == dictionary.ErrorNoDef {
 		fmt.Printf("no definition for: %s\n", searchWord)
 		return
+	} else if errors.As(err, &partial) {
+		logger.Warnln("Partial result:", partial)
 	} else if err != nil {
 		logger.Errorln("Search error:", err)
 		return
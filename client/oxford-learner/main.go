@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -25,7 +26,7 @@ func main() {
 	argsWithoutProg := os.Args[1:]
 
 	searchWord := strings.Join(argsWithoutProg, " ")
-	results, err := dict.Search(searchWord)
+	results, err := dict.Search(context.Background(), searchWord)
 	if err == dictionary.ErrorNoDef {
 		fmt.Printf("no definition for: %s\n", searchWord)
 		return
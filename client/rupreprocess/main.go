@@ -1,22 +1,33 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"os"
 	"strings"
 
-	"github.com/s8508235/tui-dictionary/pkg/tools"
+	"github.com/s8508235/tui-dictionary/pkg/morph"
 )
 
 func main() {
+	morphPath := flag.String("morph", "", "Russian morphology dictionary for offline preprocessing")
+	flag.Parse()
 
-	argsWithoutProg := os.Args[1:]
-	searchWord := strings.Join(argsWithoutProg, " ")
-	processed, err := tools.RussianPreprocess(searchWord)
+	preprocessor := morph.Chain{morph.RussianOnline}
+	if len(*morphPath) != 0 {
+		offline, err := morph.LoadRussian(*morphPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		preprocessor = morph.Chain{offline, morph.RussianOnline}
+	}
+	searchWord := strings.Join(flag.Args(), " ")
+	processed, err := preprocessor.Lemmas(context.Background(), searchWord)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	fmt.Println(processed)
+	fmt.Println(strings.Join(processed, "\n"))
 
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/s8508235/tui-dictionary/pkg/log"
+	"github.com/sirupsen/logrus"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+)
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Printf("usage: %s <path to .ifo> <word>\n", os.Args[0])
+		return
+	}
+	logger := log.New()
+	dict, err := dictionary.NewStarDictDictionary(logger, os.Args[1])
+	if err != nil {
+		logger.Errorln("Fail to init dictionary:", err)
+		return
+	}
+	logger.SetLevel(logrus.DebugLevel)
+
+	searchWord := strings.Join(os.Args[2:], " ")
+	results, err := dict.Search(context.Background(), searchWord)
+	if err == dictionary.ErrorNoDef {
+		fmt.Printf("no definition for: %s\n", searchWord)
+		return
+	} else if err != nil {
+		logger.Errorln("Search error:", err)
+		return
+	}
+
+	fmt.Printf("definition: %s\n", results)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/s8508235/tui-dictionary/pkg/anki"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+	"github.com/s8508235/tui-dictionary/pkg/output"
+)
+
+// exportAnki reads saved words of targets into a deck package at path,
+// format of each target is by its extension if format is empty
+func exportAnki(path string, pkg *anki.Package, format output.Format, targets []string) error {
+	for _, target := range targets {
+		targetFormat := format
+		if len(targetFormat) == 0 {
+			var err error
+			if targetFormat, err = output.FormatOf(target); err != nil {
+				return fmt.Errorf("%s: %w", target, err)
+			}
+		}
+		entries, err := output.Read(target, targetFormat)
+		if err != nil {
+			return fmt.Errorf("%s: %w", target, err)
+		}
+		for idx, entry := range entries {
+			// Quizlet and Markdown targets do not keep language, which is part of note GUID
+			if len(entry.Language) != 0 {
+				continue
+			}
+			if lang, err := language.Detect(entry.Word); err == nil {
+				entries[idx].Language = lang.ID
+			}
+		}
+		// a note without definition has an empty back
+		entries = slices.DeleteFunc(entries, func(entry output.Entry) bool {
+			return len(entry.Definitions) == 0
+		})
+		pkg.Add(entries...)
+	}
+	return pkg.WriteFile(path)
+}
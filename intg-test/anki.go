@@ -0,0 +1,174 @@
+package main
+
+import (
+	"archive/zip"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/anki"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/output"
+)
+
+func (i *integrationTest) testAnki() {
+	const name = "anki"
+	fail := i.failer(name)
+	dir, err := os.MkdirTemp("", "tui-dictionary-anki")
+	if err != nil {
+		fail("[temp dir]: %s", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	divest := output.Entry{
+		Word:        "divest",
+		Language:    entity.English,
+		Definitions: []entity.Definition{{Text: "to sell", Source: "first"}},
+		SavedAt:     time.Now(),
+	}
+	updated := divest
+	updated.Definitions = []entity.Definition{{Text: "to deprive", Source: "second"}}
+	house := output.Entry{Word: "дом", Language: entity.Russian, Definitions: []entity.Definition{{Text: "house"}}}
+	if anki.GUID(divest) != anki.GUID(updated) || anki.GUID(divest) == anki.GUID(house) {
+		fail("[guid]: not stable by word and language")
+	}
+	stressed := house
+	stressed.Word = "до́м"
+	if anki.GUID(stressed) != anki.GUID(house) {
+		fail("[guid]: stress mark changes guid")
+	}
+
+	pkg := anki.New("Vocabulary::Weekly", anki.Reversed)
+	pkg.Tags = []string{"weekly"}
+	pkg.Add(divest, house, updated)
+	path := filepath.Join(dir, "deck.apkg")
+	if err := pkg.WriteFile(path); err != nil {
+		fail("[write]: %s", err)
+		return
+	}
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		fail("[zip]: %s", err)
+		return
+	}
+	defer archive.Close()
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			fail("[zip %s]: %s", file.Name, err)
+			return
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			fail("[zip %s]: %s", file.Name, err)
+			return
+		}
+		files[file.Name] = string(data)
+	}
+	if files["media"] != "{}" {
+		fail("[media]: got %q", files["media"])
+	}
+	collection := filepath.Join(dir, "collection.anki2")
+	if err := os.WriteFile(collection, []byte(files["collection.anki2"]), 0600); err != nil {
+		fail("[collection]: %s", err)
+		return
+	}
+	db, err := sql.Open("sqlite", collection)
+	if err != nil {
+		fail("[collection]: %s", err)
+		return
+	}
+	defer db.Close()
+	var notes, cards int
+	var decks string
+	if err := db.QueryRow(`SELECT (SELECT count(*) FROM notes), (SELECT count(*) FROM cards), decks FROM col`).Scan(&notes, &cards, &decks); err != nil {
+		fail("[collection]: %s", err)
+		return
+	}
+	if notes != 2 || cards != 4 || !strings.Contains(decks, `"Vocabulary::Weekly"`) {
+		fail("[collection]: %d notes %d cards in %s", notes, cards, decks)
+	}
+	var fields, tags string
+	if err := db.QueryRow(`SELECT flds, tags FROM notes WHERE guid = ?`, anki.GUID(divest)).Scan(&fields, &tags); err != nil {
+		fail("[note]: %s", err)
+	} else if fields != "divest\x1f<ol><li>to deprive</li></ol>" || tags != " tui-dictionary english second weekly " {
+		fail("[note]: got %q %q", fields, tags)
+	}
+
+	testTargets(dir, divest, fail)
+}
+
+// testTargets checks that only files written here are exported when no target is given
+func testTargets(dir string, entry output.Entry, fail func(format string, args ...interface{})) {
+	dir = filepath.Join(dir, "targets")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		fail("[targets]: %s", err)
+		return
+	}
+	others := map[string]string{
+		"README.md":    "## Overview\nA simple TUI program to search dictionary online\n",
+		"CHANGELOG.md": "## v0.1.2 (2022-09-22)\n\n### Fix\n\n- remove deprecate ioutil\n",
+		"notes.txt":    "things to buy\nmilk\n",
+		"data.csv":     "word,meaning\ndivest,to sell\n",
+		"log.jsonl":    "{\"level\":\"info\"}\n",
+	}
+	for name, content := range others {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			fail("[targets %s]: %s", name, err)
+		}
+	}
+	other := filepath.Join(dir, "other.db")
+	if db, err := sql.Open("sqlite", other); err != nil {
+		fail("[targets other.db]: %s", err)
+	} else {
+		if _, err := db.Exec(`CREATE TABLE words (id INTEGER PRIMARY KEY)`); err != nil {
+			fail("[targets other.db]: %s", err)
+		}
+		db.Close()
+	}
+	want := make([]string, 0)
+	for _, format := range output.Formats() {
+		path := filepath.Join(dir, "words"+output.Extension(format))
+		w, err := output.Open(path, format)
+		if err != nil {
+			fail("[targets %s]: %s", format, err)
+			continue
+		}
+		if err := w.Write(entry); err != nil {
+			fail("[targets %s]: %s", format, err)
+		}
+		if err := w.Close(); err != nil {
+			fail("[targets %s]: %s", format, err)
+		}
+		want = append(want, path)
+	}
+	targets, err := output.Targets(dir)
+	if err != nil {
+		fail("[targets]: %s", err)
+		return
+	}
+	sort.Strings(want)
+	sort.Strings(targets)
+	if strings.Join(targets, ",") != strings.Join(want, ",") {
+		fail("[targets]: got %v", targets)
+	}
+	if _, err := output.Read(other, output.SQLite); !errors.Is(err, output.ErrForeignStore) {
+		fail("[read other.db]: %v", err)
+	}
+	// other database is not migrated by looking at it or reading it
+	if db, err := sql.Open("sqlite", other); err == nil {
+		defer db.Close()
+		var version int
+		if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil || version != 0 {
+			fail("[targets other.db]: version %d %v", version, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/dictproto"
+	"github.com/s8508235/tui-dictionary/pkg/dictserver"
+)
+
+// flakyListener breaks the first connections once client sends a command and records every command
+type flakyListener struct {
+	net.Listener
+	mu       sync.Mutex
+	broken   int
+	commands []string
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	broken := l.broken > 0
+	if broken {
+		l.broken--
+	}
+	return &recordingConn{Conn: conn, listener: l, broken: broken}, nil
+}
+
+func (l *flakyListener) count(command string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, c := range l.commands {
+		if strings.HasPrefix(c, command) {
+			n++
+		}
+	}
+	return n
+}
+
+type recordingConn struct {
+	net.Conn
+	listener *flakyListener
+	broken   bool
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	if c.broken {
+		_ = c.Conn.Close()
+		return 0, io.EOF
+	}
+	n, err := c.Conn.Read(b)
+	c.listener.mu.Lock()
+	c.listener.commands = append(c.listener.commands, strings.Split(strings.TrimSpace(string(b[:n])), "\r\n")...)
+	c.listener.mu.Unlock()
+	return n, err
+}
+
+// startFakeDICTServer serves fake dictionaries over DICT protocol on a random local port
+func (i *integrationTest) startFakeDICTServer(ctx context.Context, broken int) (*flakyListener, error) {
+	first := &fakeDictionary{name: "first", definitions: map[string][]string{"divest": {"to sell", "to deprive"}}}
+	second := &fakeDictionary{name: "second", definitions: map[string][]string{"divest": {"to get rid of"}}}
+	slow := &fakeDictionary{name: "slow", delay: time.Second}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	listener := &flakyListener{Listener: l, broken: broken}
+	server := dictserver.New(i.logger, []dictionary.Interface{first, second, slow})
+	go func() {
+		if err := server.Serve(ctx, listener); err != nil {
+			i.logger.Errorln("fake dict server:", err)
+		}
+	}()
+	return listener, nil
+}
+
+func (i *integrationTest) testDICTClient() {
+	const name = "dict-client"
+	fail := i.failer(name)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener, err := i.startFakeDICTServer(ctx, 0)
+	if err != nil {
+		fail("[start]: %s", err)
+		return
+	}
+	client, err := dictionary.NewDICTClient(i.logger, "tcp", listener.Addr().String(), "first", "second")
+	if err != nil {
+		fail("[connect]: %s", err)
+		return
+	}
+	defer client.Close()
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			definitions, err := client.Search(ctx, "divest")
+			if err != nil || len(definitions) != 2 || definitions[1].Source != "second" {
+				fail("[concurrent]: %d definitions, %v", len(definitions), err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := client.Search(ctx, "unknown"); !errors.Is(err, dictionary.ErrorNoDef) {
+		fail("[no definition]: %v", err)
+	}
+	if _, err := client.SearchDatabase(ctx, "nothing", "divest"); !errors.Is(err, dictionary.ErrUnknownDatabase) {
+		fail("[unknown database]: %v", err)
+	}
+	if databases, err := client.ShowDatabases(ctx); err != nil || len(databases) != 3 {
+		fail("[show databases]: %v %v", databases, err)
+	}
+	if matches, err := client.Match(ctx, "exact", "divest"); err != nil || len(matches) != 1 {
+		fail("[match]: %v %v", matches, err)
+	}
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	if _, err := client.SearchDatabase(timeoutCtx, "slow", "divest"); !errors.Is(err, context.DeadlineExceeded) {
+		fail("[timeout]: %v", err)
+	}
+	timeoutCancel()
+	if _, err := client.Search(ctx, "divest"); err != nil {
+		fail("[after timeout]: %v", err)
+	}
+
+	// the first two connections break right after banner
+	flaky, err := i.startFakeDICTServer(ctx, 2)
+	if err != nil {
+		fail("[start flaky]: %s", err)
+		return
+	}
+	flakyClient, err := dictionary.NewDICTClient(i.logger, "tcp", flaky.Addr().String(), "first")
+	if err != nil {
+		fail("[connect flaky]: %s", err)
+		return
+	}
+	defer flakyClient.Close()
+	flakyClient.Pool().Backoff = 10 * time.Millisecond
+	if definitions, err := flakyClient.Search(ctx, "divest"); err != nil || len(definitions) != 1 {
+		fail("[reconnect]: %d definitions, %v", len(definitions), err)
+	}
+
+	pool := dictproto.NewPool(i.logger, "tcp", listener.Addr().String(), 50*time.Millisecond)
+	defer pool.Close()
+	statusBefore := listener.count("STATUS")
+	if err := pool.Do(ctx, func(conn *dictproto.Conn) error {
+		_, err := conn.Define("first", "divest")
+		return err
+	}); err != nil {
+		fail("[pool]: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if listener.count("STATUS")-statusBefore < 2 {
+		fail("[keepalive]: no STATUS sent on idle connection")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/httpapi"
+)
+
+// fakeDictionary answers from a fixed table without network
+type fakeDictionary struct {
+	name        string
+	definitions map[string][]string
+	err         error
+	delay       time.Duration
+}
+
+func (f *fakeDictionary) Search(ctx context.Context, word string) ([]entity.Definition, error) {
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	texts, ok := f.definitions[word]
+	if !ok {
+		return nil, dictionary.ErrorNoDef
+	}
+	result := make([]entity.Definition, 0, len(texts))
+	for idx, text := range texts {
+		result = append(result, entity.Definition{Text: text, Source: f.name, Sense: idx + 1})
+	}
+	return result, nil
+}
+
+func (f *fakeDictionary) GetName() string {
+	return f.name
+}
+
+func (i *integrationTest) testHTTPAPI() {
+	const name = "httpapi"
+	fail := i.failer(name)
+
+	first := &fakeDictionary{name: "first", definitions: map[string][]string{"divest": {"to sell", "to deprive"}}}
+	second := &fakeDictionary{name: "second", definitions: map[string][]string{"divest": {"to get rid of"}}}
+	broken := &fakeDictionary{name: "broken", err: errors.New("connection refused")}
+	slow := &fakeDictionary{name: "slow", delay: time.Second}
+	prefer := &dictionary.MyPrefer{Name: "prefer", Dictionaries: []dictionary.Interface{first, second, broken}}
+	handler := httpapi.New(i.logger, []dictionary.Interface{prefer, first, broken, slow},
+		map[entity.DictionaryLanguage]string{entity.English: "prefer"})
+	handler.Timeout = 100 * time.Millisecond
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	define := func(query url.Values) (int, httpapi.DefineResponse) {
+		var body httpapi.DefineResponse
+		resp, err := http.Get(server.URL + "/v1/define?" + query.Encode())
+		if err != nil {
+			fail("[%s]: %s", query.Encode(), err)
+			return 0, body
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				fail("[%s]: decode: %s", query.Encode(), err)
+			}
+		}
+		return resp.StatusCode, body
+	}
+
+	status, body := define(url.Values{"word": {"divest"}})
+	if status != http.StatusOK {
+		fail("[default source]: status %d", status)
+	} else if len(body.Results) != 3 || body.Results[0].Source != "first" || len(body.Results[0].Definitions) != 2 ||
+		body.Results[2].Error == "" {
+		fail("[default source]: unexpected results %+v", body.Results)
+	}
+	statusCases := []struct {
+		category string
+		query    url.Values
+		status   int
+	}{
+		{"single source", url.Values{"word": {"divest"}, "source": {"first"}}, http.StatusOK},
+		{"no definition", url.Values{"word": {"unknown"}, "source": {"first"}}, http.StatusNotFound},
+		{"aggregated no definition", url.Values{"word": {"unknown"}, "source": {"prefer"}}, http.StatusBadGateway},
+		{"unknown source", url.Values{"word": {"divest"}, "source": {"nothing"}}, http.StatusNotFound},
+		{"missing word", url.Values{}, http.StatusBadRequest},
+		{"invalid word", url.Values{"word": {"дом"}, "lang": {"en"}}, http.StatusBadRequest},
+		{"unknown language", url.Values{"word": {"divest"}, "lang": {"fr"}}, http.StatusBadRequest},
+		{"source failure", url.Values{"word": {"divest"}, "source": {"broken"}}, http.StatusBadGateway},
+		{"timeout", url.Values{"word": {"divest"}, "source": {"slow"}}, http.StatusGatewayTimeout},
+	}
+	for _, c := range statusCases {
+		if status, _ := define(c.query); status != c.status {
+			fail("[%s]: status %d, want %d", c.category, status, c.status)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status, body := define(url.Values{"word": {"divest"}}); status != http.StatusOK || len(body.Results) != 3 {
+				fail("[concurrent]: status %d with %d results", status, len(body.Results))
+			}
+		}()
+	}
+	wg.Wait()
+}
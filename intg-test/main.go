@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/s8508235/tui-dictionary/pkg/dictionary"
 	"github.com/sirupsen/logrus"
@@ -11,6 +13,7 @@ type integrationTest struct {
 	logger             *logrus.Logger
 	failedDictionaries map[string][]string
 	dictionaries       []dictionary.Interface
+	mu                 sync.Mutex
 }
 
 func checkEmptyMap(m map[string][]string) bool {
@@ -22,6 +25,16 @@ func checkEmptyMap(m map[string][]string) bool {
 	return true
 }
 
+// failer starts failures of name and gives a function recording them, safe to call from goroutines
+func (i *integrationTest) failer(name string) func(format string, args ...interface{}) {
+	i.failedDictionaries[name] = make([]string, 0)
+	return func(format string, args ...interface{}) {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		i.failedDictionaries[name] = append(i.failedDictionaries[name], fmt.Sprintf(format, args...))
+	}
+}
+
 func main() {
 	englishWord := "divest"
 	splitedEnglishWord := "tie up"
@@ -49,6 +62,11 @@ func main() {
 		dictionaries:       []dictionary.Interface{oxford, cambridge, webster, learner},
 		failedDictionaries: make(map[string][]string),
 	}
+	intgTest.testHTTPAPI()
+	intgTest.testDICTClient()
+	intgTest.testOutput()
+	intgTest.testAnki()
+	intgTest.testStore()
 	intgTest.testDictionary("normal", englishWord)
 	intgTest.testDictionary("splitted", splitedEnglishWord)
 
@@ -65,7 +83,7 @@ func (i *integrationTest) testDictionary(testCategory, word string) {
 		if _, exist := i.failedDictionaries[name]; !exist {
 			i.failedDictionaries[name] = make([]string, 0)
 		}
-		_, err := dict.Search(word)
+		_, err := dict.Search(context.Background(), word)
 		if err == dictionary.ErrorNoDef {
 			i.failedDictionaries[name] = append(i.failedDictionaries[name], fmt.Sprintf("[%s]: no definitions", testCategory))
 		} else if err != nil {
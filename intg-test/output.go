@@ -0,0 +1,443 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/output"
+)
+
+func (i *integrationTest) testOutput() {
+	const name = "output"
+	fail := i.failer(name)
+	dir, err := os.MkdirTemp("", "tui-dictionary-output")
+	if err != nil {
+		fail("[temp dir]: %s", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	entry := output.Entry{
+		Word:     "divest",
+		Language: entity.English,
+		Definitions: []entity.Definition{
+			{Text: "to sell", PartOfSpeech: "verb", Source: "first dict", Examples: []string{"divest, the \"asset\""}},
+			{Text: "to deprive", Source: "second"},
+		},
+		SavedAt: time.Now(),
+	}
+	// write appends one entry to path, existing content is kept
+	write := func(format output.Format, path, existing string) string {
+		if len(existing) != 0 {
+			if err := os.WriteFile(path, []byte(existing), 0600); err != nil {
+				fail("[%s]: %s", format, err)
+				return ""
+			}
+		}
+		w, err := output.Open(path, format)
+		if err != nil {
+			fail("[%s]: open: %s", format, err)
+			return ""
+		}
+		if err := w.Write(entry); err != nil {
+			fail("[%s]: write: %s", format, err)
+		}
+		if err := w.Close(); err != nil {
+			fail("[%s]: close: %s", format, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fail("[%s]: read: %s", format, err)
+		}
+		return string(data)
+	}
+
+	for _, ext := range output.Extensions() {
+		format, err := output.FormatOf("target" + strings.ToUpper(ext))
+		if err != nil || output.Extension(format) != ext {
+			fail("[format of %s]: %s %v", ext, format, err)
+		}
+	}
+	if _, err := output.FormatOf("target.doc"); !errors.Is(err, output.ErrUnknownFormat) {
+		fail("[unknown extension]: %v", err)
+	}
+
+	quizlet := write(output.Quizlet, filepath.Join(dir, "q.txt"), "apple\tfruit\n")
+	if want := "apple\tfruit\n\n\ndivest\t(verb) to sell e.g. divest, the \"asset\";to deprive\n"; quizlet != want {
+		fail("[quizlet]: got %q", quizlet)
+	}
+
+	ankiPath := filepath.Join(dir, "a.csv")
+	write(output.AnkiCSV, ankiPath, "")
+	anki := write(output.AnkiCSV, ankiPath, "")
+	body, _ := strings.CutPrefix(anki, "#separator:Comma\n#html:true\n#columns:Front,Back,Tags\n#tags column:3\n")
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		fail("[anki-csv]: %s in %q", err, anki)
+	} else if len(records) != 2 || records[1][0] != "divest" || records[1][2] != "tui-dictionary english first_dict second" ||
+		!strings.Contains(records[1][1], "<li>(verb) to sell<br><i>divest, the &#34;asset&#34;</i></li>") {
+		fail("[anki-csv]: got %q", anki)
+	}
+	if _, err := output.Open(ankiPath, output.AnkiTSV); !errors.Is(err, output.ErrCorrupt) {
+		fail("[anki-tsv on csv]: %v", err)
+	}
+	tsv := write(output.AnkiTSV, filepath.Join(dir, "a.tsv"), "")
+	if !strings.HasPrefix(tsv, "#separator:Tab\n") || !strings.Contains(tsv, "\ndivest\t<ol>") {
+		fail("[anki-tsv]: got %q", tsv)
+	}
+
+	jsonlPath := filepath.Join(dir, "w.jsonl")
+	jsonl := write(output.JSONLines, jsonlPath, `{"word":"apple","definitions":[]}`)
+	lines := strings.Split(strings.TrimSuffix(jsonl, "\n"), "\n")
+	var saved output.Entry
+	if len(lines) != 2 {
+		fail("[jsonl]: got %q", jsonl)
+	} else if err := json.Unmarshal([]byte(lines[1]), &saved); err != nil {
+		fail("[jsonl]: %s", err)
+	} else if saved.Word != "divest" || len(saved.Definitions) != 2 || saved.Definitions[0].Examples[0] != entry.Definitions[0].Examples[0] {
+		fail("[jsonl]: got %+v", saved)
+	}
+	if err := os.WriteFile(jsonlPath, []byte(jsonl+`{"word":"app`), 0600); err != nil {
+		fail("[jsonl]: %s", err)
+	} else if _, err := output.Open(jsonlPath, output.JSONLines); !errors.Is(err, output.ErrCorrupt) {
+		fail("[jsonl broken line]: %v", err)
+	}
+
+	markdown := write(output.Markdown, filepath.Join(dir, "w.md"), "# Words\n")
+	if want := "# Words\n\n## divest\n\n1. (verb) to sell _(first dict)_\n   - divest, the \"asset\"\n2. to deprive _(second)_\n\n"; markdown != want {
+		fail("[markdown]: got %q", markdown)
+	}
+
+	if w, err := output.Open(os.DevNull, output.Quizlet); err != nil || w != output.Discard {
+		fail("[dev null]: %v", err)
+	}
+
+	// every format reads back what it writes, as far as it keeps
+	for _, format := range output.Formats() {
+		path := filepath.Join(dir, "read"+output.Extension(format))
+		write(format, path, "")
+		entries, err := output.Read(path, format)
+		if err != nil {
+			fail("[read %s]: %s", format, err)
+			continue
+		}
+		if len(entries) != 1 || entries[0].Word != entry.Word || len(entries[0].Definitions) != 2 {
+			fail("[read %s]: got %+v", format, entries)
+			continue
+		}
+		if format != output.Quizlet && (entries[0].Definitions[0].Text != "(verb) to sell" && entries[0].Definitions[0].Text != "to sell" ||
+			len(entries[0].Definitions[0].Examples) != 1) {
+			fail("[read %s]: got definitions %+v", format, entries[0].Definitions)
+		}
+	}
+
+	// replace keeps other words and content between entries
+	apple := output.Entry{Word: "apple", Language: entity.English, Definitions: []entity.Definition{{Text: "fruit"}}}
+	updated := entry
+	updated.Word = "Divest"
+	updated.Definitions = []entity.Definition{{Text: "to rid"}}
+	for _, format := range output.Formats() {
+		path := filepath.Join(dir, "replace"+output.Extension(format))
+		if format == output.Markdown {
+			if err := os.WriteFile(path, []byte("# Words\n\n"), 0600); err != nil {
+				fail("[replace %s]: %s", format, err)
+				continue
+			}
+		}
+		w, err := output.Open(path, format)
+		if err != nil {
+			fail("[replace %s]: open: %s", format, err)
+			continue
+		}
+		for _, e := range []output.Entry{entry, apple, entry} {
+			if err := w.Write(e); err != nil {
+				fail("[replace %s]: write: %s", format, err)
+			}
+		}
+		if err := w.Replace(updated); err != nil {
+			fail("[replace %s]: %s", format, err)
+		}
+		// the writer still appends after replacing
+		if err := w.Write(apple); err != nil {
+			fail("[replace %s]: write after replace: %s", format, err)
+		}
+		if err := w.Close(); err != nil {
+			fail("[replace %s]: close: %s", format, err)
+		}
+		entries, err := output.Read(path, format)
+		if err != nil {
+			fail("[replace %s]: read: %s", format, err)
+			continue
+		}
+		words := make([]string, 0, len(entries))
+		for _, e := range entries {
+			words = append(words, e.Word)
+		}
+		index := output.NewIndex(entries)
+		if saved := index.Get("divest"); len(saved) != 1 || len(saved[0].Definitions) != 1 || saved[0].Definitions[0].Text != "to rid" {
+			fail("[replace %s]: got %v %+v", format, words, saved)
+		}
+		data, _ := os.ReadFile(path)
+		if format == output.Markdown && !strings.HasPrefix(string(data), "# Words\n\n## apple") ||
+			format == output.AnkiTSV && !strings.HasPrefix(string(data), "#separator:Tab\n") {
+			fail("[replace %s]: header lost in %q", format, data)
+		}
+	}
+
+	// rewrite reorders and edits every entry, the writer still appends after it
+	edited := entry
+	edited.Definitions = []entity.Definition{{Text: "to rid"}, {Text: "to sell"}}
+	for _, format := range output.Formats() {
+		path := filepath.Join(dir, "rewrite"+output.Extension(format))
+		if format == output.Markdown {
+			if err := os.WriteFile(path, []byte("# Words\n\n"), 0600); err != nil {
+				fail("[rewrite %s]: %s", format, err)
+				continue
+			}
+		}
+		w, err := output.Open(path, format)
+		if err != nil {
+			fail("[rewrite %s]: open: %s", format, err)
+			continue
+		}
+		for _, e := range []output.Entry{entry, apple} {
+			if err := w.Write(e); err != nil {
+				fail("[rewrite %s]: write: %s", format, err)
+			}
+		}
+		if err := w.Rewrite([]output.Entry{apple, edited}); err != nil {
+			fail("[rewrite %s]: %s", format, err)
+		}
+		if err := w.Write(output.Entry{Word: "cherry", Language: entity.English, Definitions: []entity.Definition{{Text: "fruit"}}}); err != nil {
+			fail("[rewrite %s]: write after rewrite: %s", format, err)
+		}
+		entries, err := w.Entries()
+		if err != nil {
+			fail("[rewrite %s]: entries: %s", format, err)
+		}
+		if err := w.Close(); err != nil {
+			fail("[rewrite %s]: close: %s", format, err)
+		}
+		words := make([]string, 0, len(entries))
+		for _, e := range entries {
+			words = append(words, e.Word)
+		}
+		if strings.Join(words, ",") != "apple,divest,cherry" || len(entries[1].Definitions) != 2 || entries[1].Definitions[0].Text != "to rid" {
+			fail("[rewrite %s]: got %v %+v", format, words, entries)
+		}
+		data, _ := os.ReadFile(path)
+		if format == output.Markdown && !strings.HasPrefix(string(data), "# Words\n\n## apple") ||
+			format == output.AnkiCSV && !strings.HasPrefix(string(data), "#separator:Comma\n") {
+			fail("[rewrite %s]: header lost in %q", format, data)
+		}
+	}
+	// rewriting Anki targets keeps tags of source dictionaries, which are not kept by definitions read back
+	for _, format := range []output.Format{output.AnkiCSV, output.AnkiTSV} {
+		path := filepath.Join(dir, "tags"+output.Extension(format))
+		w, err := output.Open(path, format)
+		if err != nil {
+			fail("[tags %s]: open: %s", format, err)
+			continue
+		}
+		for _, e := range []output.Entry{entry, apple} {
+			if err := w.Write(e); err != nil {
+				fail("[tags %s]: write: %s", format, err)
+			}
+		}
+		entries, err := w.Entries()
+		if err != nil || len(entries) != 2 {
+			fail("[tags %s]: entries: %v %v", format, entries, err)
+			w.Close()
+			continue
+		}
+		entries[0], entries[1] = entries[1], entries[0]
+		if err := w.Rewrite(entries); err != nil {
+			fail("[tags %s]: %s", format, err)
+		}
+		w.Close()
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), "tui-dictionary english first_dict second") {
+			fail("[tags %s]: tags lost in %q", format, data)
+		}
+	}
+	merged := output.MergeDefinitions([]output.Entry{apple}, []entity.Definition{{Text: "fruit"}, {Text: "tree"}})
+	if len(merged) != 2 || merged[1].Text != "tree" || merged[1].Sense != 2 {
+		fail("[merge]: got %+v", merged)
+	}
+}
+
+func (i *integrationTest) testStore() {
+	const name = "store"
+	fail := i.failer(name)
+	dir, err := os.MkdirTemp("", "tui-dictionary-store")
+	if err != nil {
+		fail("[temp dir]: %s", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "words.db")
+	if format, err := output.FormatOf(path); err != nil || format != output.SQLite {
+		fail("[format]: %s %v", format, err)
+	}
+
+	first := output.Entry{
+		Word:        "divest",
+		Language:    entity.English,
+		Definitions: []entity.Definition{{Text: "to sell", Source: "first", Labels: []string{"formal"}}},
+		SavedAt:     time.Now().Add(-time.Hour),
+	}
+	second := first
+	second.Definitions = []entity.Definition{{Text: "to sell", Source: "first"}, {Text: "to deprive", Source: "second"}}
+	second.SavedAt = time.Now()
+	w, err := output.Open(path, output.SQLite)
+	if err != nil {
+		fail("[open]: %s", err)
+		return
+	}
+	recorder, ok := w.(output.LookupRecorder)
+	if !ok {
+		fail("[lookup]: store does not count lookups")
+	} else {
+		for _, word := range []string{"divest", "divest", "apple"} {
+			if err := recorder.RecordLookup(word, entity.English); err != nil {
+				fail("[lookup]: %s", err)
+			}
+		}
+	}
+	for _, entry := range []output.Entry{first, second} {
+		if err := w.Write(entry); err != nil {
+			fail("[write]: %s", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		fail("[close]: %s", err)
+	}
+
+	// reopening does not migrate again
+	entries, err := output.Read(path, output.SQLite)
+	if err != nil {
+		fail("[read]: %s", err)
+	} else if len(entries) != 1 || len(entries[0].Definitions) != 2 || entries[0].Definitions[0].Labels[0] != "formal" ||
+		entries[0].Language != entity.English || !entries[0].SavedAt.Equal(second.SavedAt.Truncate(time.Nanosecond)) {
+		fail("[read]: got %+v", entries)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		fail("[counts]: %s", err)
+		return
+	}
+	defer db.Close()
+	var lookups, saves int
+	if err := db.QueryRow(`SELECT lookup_count, save_count FROM words WHERE word = 'divest'`).Scan(&lookups, &saves); err != nil {
+		fail("[counts]: %s", err)
+	} else if lookups != 2 || saves != 2 {
+		fail("[counts]: %d lookups %d saves", lookups, saves)
+	}
+	// case and stress marks of Russian words are ignored by store and index alike
+	ru := filepath.Join(dir, "ru.db")
+	if w, err := output.Open(ru, output.SQLite); err != nil {
+		fail("[stress]: %s", err)
+	} else {
+		for _, word := range []string{"дом", "До\u0301м"} {
+			if err := w.Write(output.Entry{Word: word, Language: entity.Russian, Definitions: []entity.Definition{{Text: "house " + word}}}); err != nil {
+				fail("[stress]: %s", err)
+			}
+		}
+		if output.AppendsDuplicates(w) {
+			fail("[stress]: store appends the same word")
+		}
+		entries, err := w.Entries()
+		if err != nil || len(entries) != 1 || len(entries[0].Definitions) != 2 {
+			fail("[stress]: got %+v %v", entries, err)
+		}
+		if saved := output.NewIndex(entries).Get("ДО\u0301М"); len(saved) != 1 || !output.SameWord(saved[0].Word, "дом") {
+			fail("[stress]: index got %+v", saved)
+		}
+		w.Close()
+	}
+	// stores of the first schema are upgraded with words differing in case or stress merged
+	v1 := filepath.Join(dir, "v1.db")
+	if err := writeFirstSchemaStore(v1); err != nil {
+		fail("[upgrade]: %s", err)
+	} else if entries, err := output.Read(v1, output.SQLite); err != nil || len(entries) != 2 ||
+		len(entries[0].Definitions) != 2 || len(entries[1].Definitions) != 2 {
+		fail("[upgrade]: got %+v %v", entries, err)
+	} else if w, err := output.Open(v1, output.SQLite); err != nil {
+		fail("[upgrade]: %s", err)
+	} else {
+		if err := w.Write(output.Entry{Word: "DIVEST", Language: entity.English, Definitions: []entity.Definition{{Text: "to strip"}}}); err != nil {
+			fail("[upgrade]: %s", err)
+		}
+		if entries, err := w.Entries(); err != nil || len(entries) != 2 || len(entries[0].Definitions) != 3 {
+			fail("[upgrade]: got %+v %v", entries, err)
+		}
+		w.Close()
+	}
+	if _, err := db.Exec(`PRAGMA user_version = 99`); err != nil {
+		fail("[newer schema]: %s", err)
+	} else if _, err := output.Open(path, output.SQLite); !errors.Is(err, output.ErrNewerSchema) {
+		fail("[newer schema]: %v", err)
+	}
+	if _, err := output.Read(filepath.Join(dir, "missing.db"), output.SQLite); !errors.Is(err, os.ErrNotExist) {
+		fail("[missing]: %v", err)
+	}
+}
+
+// writeFirstSchemaStore writes a store as the first version of its schema did, with variants of the same words
+func writeFirstSchemaStore(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE words (
+		id INTEGER PRIMARY KEY,
+		word TEXT NOT NULL,
+		language TEXT NOT NULL DEFAULT '',
+		lookup_count INTEGER NOT NULL DEFAULT 0,
+		save_count INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		UNIQUE (word, language)
+	);
+	CREATE TABLE definitions (
+		id INTEGER PRIMARY KEY,
+		word_id INTEGER NOT NULL REFERENCES words (id) ON DELETE CASCADE,
+		text TEXT NOT NULL,
+		part_of_speech TEXT NOT NULL DEFAULT '',
+		source TEXT NOT NULL DEFAULT '',
+		sense INTEGER NOT NULL DEFAULT 0,
+		labels TEXT NOT NULL DEFAULT '[]',
+		examples TEXT NOT NULL DEFAULT '[]',
+		saved_at TIMESTAMP NOT NULL,
+		UNIQUE (word_id, text, source)
+	);
+	CREATE INDEX ix_definitions_word_id ON definitions (word_id);
+	PRAGMA user_version = 1;`)
+	if err != nil {
+		return err
+	}
+	at := time.Now().UTC()
+	for idx, word := range []string{"Divest", "divest", "Дом", "до\u0301м"} {
+		lang := entity.English
+		if idx > 1 {
+			lang = entity.Russian
+		}
+		if _, err := db.Exec(`INSERT INTO words (id, word, language, lookup_count, save_count, created_at, updated_at) VALUES (?, ?, ?, 1, 1, ?, ?)`,
+			idx+1, word, string(lang), at, at); err != nil {
+			return err
+		}
+		if _, err := db.Exec(`INSERT INTO definitions (word_id, text, saved_at) VALUES (?, ?, ?)`, idx+1, "meaning of "+word, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
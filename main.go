@@ -1,15 +1,15 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/aaaton/golem/v4"
-	"github.com/aaaton/golem/v4/dicts/en"
 	"github.com/c-bata/go-prompt"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -18,9 +18,16 @@ import (
 	"github.com/erikgeiser/promptkit/selection"
 	"github.com/muesli/termenv"
 	"github.com/s8508235/tui-dictionary/model"
+	"github.com/s8508235/tui-dictionary/pkg/anki"
+	"github.com/s8508235/tui-dictionary/pkg/cache"
 	"github.com/s8508235/tui-dictionary/pkg/dictionary"
 	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/history"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+	"github.com/s8508235/tui-dictionary/pkg/lexicon"
 	"github.com/s8508235/tui-dictionary/pkg/log"
+	"github.com/s8508235/tui-dictionary/pkg/morph"
+	"github.com/s8508235/tui-dictionary/pkg/output"
 	"github.com/s8508235/tui-dictionary/pkg/tools"
 	"github.com/sirupsen/logrus"
 )
@@ -35,15 +42,91 @@ func targetCompleter(fileNameList []string) prompt.Completer {
 	}
 }
 
-func initialModel(logger *logrus.Logger, lemmatizer *golem.Lemmatizer,
-	dictionary dictionary.Interface, out io.Writer, lang entity.DictionaryLanguage, target string) model.Dictionary {
+// formatNames lists target formats for help message
+func formatNames() string {
+	names := make([]string, 0, len(output.Formats()))
+	for _, format := range output.Formats() {
+		names = append(names, string(format))
+	}
+	return strings.Join(names, ", ")
+}
 
-	searchWord := textinput.New()
-	if lang == entity.Russian {
-		searchWord.Placeholder = "о́пыт"
-	} else {
-		searchWord.Placeholder = "test"
+// parseFormat accepts empty format which means by target extension
+func parseFormat(s string) (output.Format, error) {
+	if len(s) == 0 {
+		return "", nil
+	}
+	return output.ParseFormat(s)
+}
+
+// localDictionary is a dictionary file on disk
+type localDictionary interface {
+	dictionary.Interface
+	Title() string
+	Language() entity.DictionaryLanguage
+}
+
+// loadLocalDictionaries opens StarDict and dictd files, broken ones are skipped
+func loadLocalDictionaries(logger *logrus.Logger, starDictDir, dictdDir string) ([]localDictionary, error) {
+	locals := make([]localDictionary, 0)
+	ifoPaths, err := dictionary.FindStarDicts(starDictDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, ifoPath := range ifoPaths {
+		starDict, err := dictionary.NewStarDictDictionary(logger, ifoPath)
+		if err != nil {
+			logger.Warnln("Skip StarDict", ifoPath, err)
+			continue
+		}
+		locals = append(locals, starDict)
+	}
+	indexPaths, err := dictionary.FindDictds(dictdDir)
+	if err != nil {
+		return nil, err
 	}
+	for _, indexPath := range indexPaths {
+		dictd, err := dictionary.NewDictdDictionary(logger, indexPath)
+		if err != nil {
+			logger.Warnln("Skip dictd database", indexPath, err)
+			continue
+		}
+		locals = append(locals, dictd)
+	}
+	return locals, nil
+}
+
+// loadLexicon fills words from word forms known by preprocessor, word lists and earlier target files
+func loadLexicon(logger *logrus.Logger, words *lexicon.Lexicon, wordLists, targets []string) {
+	if source, ok := words.Language.Preprocessor.(morph.WordSource); ok {
+		// the lemmatizer is loaded here as well so the first search does not wait for it
+		if err := source.Load(); err != nil {
+			logger.Warnln("Fail to load lemmatizer:", err)
+		}
+		if err := words.LoadWordSource(source); err != nil {
+			logger.Warnln("Fail to load lemma dictionary words:", err)
+		}
+	}
+	if err := words.LoadFiles(wordLists...); err != nil {
+		logger.Warnln("Fail to load word list:", err)
+	}
+	if err := words.LoadTargets(targets...); err != nil {
+		logger.Warnln("Fail to load words of target files:", err)
+	}
+	logger.Infoln("lexicon loaded with", words.Len(), "words")
+}
+
+// closeCache writes lookups still waiting in lookupCache
+func closeCache(logger *logrus.Logger, lookupCache *cache.Cache) {
+	if err := lookupCache.Close(); err != nil {
+		logger.Warnln("Fail to write cache:", err)
+	}
+}
+
+func initialModel(logger *logrus.Logger, dictionary dictionary.Interface, out output.Writer, lang *language.Language, target string) model.Dictionary {
+
+	searchWord := textinput.New()
+	searchWord.Placeholder = lang.Placeholder
 	searchWord.Focus()
 	s := spinner.New()
 	// https://github.com/briandowns/spinner
@@ -55,10 +138,9 @@ func initialModel(logger *logrus.Logger, lemmatizer *golem.Lemmatizer,
 		Logger:     logger,
 		Target:     target,
 		Language:   lang,
-		Choices:    make([]string, 0),
+		Choices:    make([]entity.Definition, 0),
 		Selected:   make(map[int]struct{}),
 		Out:        out,
-		Lemmatizer: lemmatizer,
 		Dictionary: dictionary,
 		SearchWord: searchWord,
 		Spinner:    s,
@@ -66,44 +148,195 @@ func initialModel(logger *logrus.Logger, lemmatizer *golem.Lemmatizer,
 }
 
 func main() {
+	searchTimeout := flag.Duration("timeout", 30*time.Second, "time limit of a single search, 0 means no limit") //nolint:gomnd
+	dedupe := flag.Bool("dedupe", false, "merge the same definitions from different dictionaries")
+	sourceTimeout := flag.Duration("source-timeout", 10*time.Second, "time limit of each dictionary in a search, 0 means no limit") //nolint:gomnd
+
+	defaultCachePath, err := cache.DefaultPath()
+	if err != nil {
+		defaultCachePath = "tui-dictionary-cache.json"
+	}
+	useCache := flag.Bool("cache", false, "keep looked up definitions on disk")
+	cachePath := flag.String("cache-path", defaultCachePath, "path of lookup cache")
+	cacheTTL := flag.Duration("cache-ttl", 7*24*time.Hour, "how long a cached lookup stays valid, 0 means forever") //nolint:gomnd
+	cacheSize := flag.Int("cache-size", 5000, "max number of cached lookups, 0 means no limit")                     //nolint:gomnd
+
+	offlineMode := flag.Bool("offline", false, "search only lookup cache and saved target files")
+
+	starDictDir := flag.String("stardict", "stardict", "directory of StarDict dictionaries (.ifo/.idx/.dict.dz)")
+	dictdDir := flag.String("dictd", "dictd", "directory of dictd databases (.index/.dict.dz)")
+
+	dictAddr := flag.String("dict-addr", "localhost:2628", "listen address of serve-dict")
+	httpAddr := flag.String("http-addr", "localhost:8080", "listen address of serve-http")
+
+	wordList := flag.String("wordlist", "/usr/share/dict/words", "comma separated word lists for spelling suggestions, one word per line")
+
+	defaultHistoryPath, err := history.DefaultPath()
+	if err != nil {
+		defaultHistoryPath = "tui-dictionary-history.json"
+	}
+	historyPath := flag.String("history-path", defaultHistoryPath, "path of search history, empty means no history")
+	historySize := flag.Int("history-size", 1000, "max number of lookups kept in history, 0 means no limit") //nolint:gomnd
+
+	headword := flag.String("headword", "lemma", "word written to target, lemma or typed")
+	ruMorph := flag.String("ru-morph", "dict.opcorpora.txt", "Russian morphology dictionary for offline lemmatization and stress (.txt, .gz or .bz2)")
+
+	outputFormat := flag.String("format", "", "format of target file: "+formatNames()+", empty means by target extension")
+
+	apkgPath := flag.String("apkg", "tui-dictionary.apkg", "path of Anki package written by export-anki")
+	ankiDeck := flag.String("anki-deck", "tui-dictionary", "deck name of export-anki")
+	ankiNoteType := flag.String("anki-note-type", "basic", "note type of export-anki, basic or reversed")
+	ankiTags := flag.String("anki-tags", "", "comma separated tags added to every note by export-anki")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\nCommands:\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "  clear-cache\tremove lookup cache")
+		fmt.Fprintln(flag.CommandLine.Output(), "  export-anki [target...]\texport saved words of targets as Anki package, every target in current directory if none")
+		fmt.Fprintln(flag.CommandLine.Output(), "  serve-dict\tserve all dictionaries over DICT protocol (RFC 2229)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  serve-http\tserve all dictionaries as JSON over HTTP")
+		fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	command := flag.Arg(0)
+	switch command {
+	case "", "serve-dict", "serve-http":
+	case "export-anki":
+		targetFormat, err := parseFormat(*outputFormat)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		noteType, err := anki.ParseNoteType(*ankiNoteType)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		targets := flag.Args()[1:]
+		if len(targets) == 0 {
+			if targets, err = output.Targets("."); err != nil {
+				fmt.Println("Fail to find target files:", err)
+				os.Exit(1)
+			}
+		}
+		pkg := anki.New(*ankiDeck, noteType)
+		if len(*ankiTags) != 0 {
+			pkg.Tags = strings.Split(*ankiTags, ",")
+		}
+		if err := exportAnki(*apkgPath, pkg, targetFormat, targets); err != nil {
+			fmt.Println("Fail to export Anki package:", err)
+			os.Exit(1)
+		}
+		fmt.Println("exported", pkg.Len(), "notes to", *apkgPath)
+		return
+	case "clear-cache":
+		if err := cache.Clear(*cachePath); err != nil {
+			fmt.Println("Fail to clear cache:", err)
+			os.Exit(1)
+		}
+		fmt.Println("cache cleared:", *cachePath)
+		return
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
 	logger := log.New()
+	if command == "serve-dict" || command == "serve-http" {
+		opts := serverOptions{
+			useCache:      *useCache,
+			cachePath:     *cachePath,
+			cacheTTL:      *cacheTTL,
+			cacheSize:     *cacheSize,
+			starDictDir:   *starDictDir,
+			dictdDir:      *dictdDir,
+			sourceTimeout: *sourceTimeout,
+			searchTimeout: *searchTimeout,
+			addr:          *dictAddr,
+		}
+		if command == "serve-http" {
+			opts.addr = *httpAddr
+		}
+		// exit only after runServer returns so batched cache writes are flushed
+		if err := runServer(logger, command, opts); err != nil {
+			logger.Errorln(err)
+			os.Exit(1)
+		}
+		return
+	}
 	logFile, err := os.OpenFile("tui-dictionary.log", os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
 		logger.Errorf("create log failed: %v\n", err)
 		os.Exit(1)
 	}
 	logger.SetOutput(logFile)
+	targetFormat, err := parseFormat(*outputFormat)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *headword != "lemma" && *headword != "typed" {
+		fmt.Println("unknown headword:", *headword)
+		os.Exit(2)
+	}
 	// logger.SetLevel(logrus.DebugLevel)
 	files, err := os.ReadDir("./")
 	if err != nil {
 		logger.Errorln("Fail to read current directory:", err)
 		return
 	}
+	// fileNameList are Quizlet targets which offline dictionary and lexicon read,
+	// targetNameList are targets of every format for completion
 	fileNameList := make([]string, 0)
+	targetNameList := make([]string, 0)
 	for _, file := range files {
-		if !file.IsDir() && filepath.Ext(file.Name()) == ".txt" {
+		if file.IsDir() {
+			continue
+		}
+		format, err := output.FormatOf(file.Name())
+		if err != nil {
+			continue
+		}
+		targetNameList = append(targetNameList, file.Name())
+		if format == output.Quizlet {
 			fileNameList = append(fileNameList, file.Name())
 		}
 	}
-	logger.Debug(strings.Join(fileNameList, ","))
+	logger.Debug(strings.Join(targetNameList, ","))
 	type langChoice struct {
-		Display    string
-		Dictionary entity.DictionaryType
-		Language   entity.DictionaryLanguage
-	}
-	choices := []langChoice{
-		{
-			Display:  "English to English",
-			Language: entity.English,
-		},
-		{
-			Display:  "Russian to English",
-			Language: entity.Russian,
-		},
-		{
-			Display:  "English to English (w/Urban)",
-			Language: entity.English,
-		},
+		language.DictionaryChoice
+		Language *language.Language
+		Local    localDictionary
+	}
+	locals, err := loadLocalDictionaries(logger, *starDictDir, *dictdDir)
+	if err != nil {
+		logger.Errorln("Fail to find local dictionaries:", err)
+		return
+	}
+	choices := make([]langChoice, 0)
+	for _, lang := range language.All() {
+		for _, dictChoice := range lang.Dictionaries {
+			if dictChoice.WithLocal && !slices.ContainsFunc(locals, func(local localDictionary) bool {
+				return local.Language() == lang.ID
+			}) {
+				continue
+			}
+			choices = append(choices, langChoice{DictionaryChoice: dictChoice, Language: lang})
+		}
+	}
+	for _, local := range locals {
+		lang, err := language.Get(local.Language())
+		if err != nil {
+			logger.Warnln("Skip local dictionary", local.GetName(), err)
+			continue
+		}
+		choices = append(choices, langChoice{
+			DictionaryChoice: language.DictionaryChoice{
+				Display: fmt.Sprintf("%s [%s]", local.Title(), local.GetName()),
+				Type:    entity.LocalDictionary,
+			},
+			Language: lang,
+			Local:    local,
+		})
 	}
 	sp := selection.New("Choose a dictionary-language combination:", choices)
 	sp.Filter = nil
@@ -128,98 +361,116 @@ func main() {
 		os.Exit(0)
 	}
 
-	var dict dictionary.Interface
-	switch choice.Dictionary {
-	case entity.EnglishMyPrefer:
-		dict, err = dictionary.NewMyPreferDictionary(logger)
-		if err != nil {
-			logger.Errorln("Fail to init dictionary:", err)
-			return
+	// cache file is also read by offline dictionary even if cache is off,
+	// a broken one is left untouched and searches go on without it
+	lookupCache, err := cache.New(*cachePath, *cacheTTL, *cacheSize)
+	if err != nil {
+		logger.Warnln("Fail to load cache, search without it:", err)
+		lookupCache = nil
+	} else {
+		defer closeCache(logger, lookupCache)
+	}
+	offline := dictionary.NewOfflineDictionary(logger, lookupCache, fileNameList)
+	var dict *dictionary.MyPrefer
+	if *offlineMode {
+		dict = &dictionary.MyPrefer{
+			Name:         "offline",
+			Dictionaries: []dictionary.Interface{offline},
 		}
-	case entity.RussianMyPrefer:
-		dict, err = dictionary.NewMyPreferRUDictionary(logger)
-		if err != nil {
-			logger.Errorln("Fail to init dictionary:", err)
-			return
+	} else {
+		if choice.Type == entity.LocalDictionary {
+			dict = &dictionary.MyPrefer{
+				Name:         choice.Local.GetName(),
+				Dictionaries: []dictionary.Interface{choice.Local},
+			}
+		} else {
+			dict, err = choice.New(logger)
+			if err != nil {
+				logger.Errorln("Fail to init dictionary:", err)
+				return
+			}
+			if choice.WithLocal {
+				for _, local := range locals {
+					if local.Language() == choice.Language.ID {
+						dict.Dictionaries = append(dict.Dictionaries, local)
+					}
+				}
+			}
 		}
-	case entity.EnglishMyPreferWithUrban:
-		dict, err = dictionary.NewMyPreferWithUrbanDictionary(logger)
-		if err != nil {
-			logger.Errorln("Fail to init dictionary:", err)
-			return
+		if *useCache && lookupCache != nil {
+			dict.UseCache(logger, lookupCache)
 		}
-	default:
-		logger.Error(entity.ErrUnknownLanguage)
-		os.Exit(1)
+		dict.Fallback = offline
 	}
-	lemmatizer, err := golem.New(en.New())
-	if err != nil {
-		logger.Errorln("Fail to init lemmatizer:", err)
-		return
+	dict.Timeout = *sourceTimeout
+	dict.Dedupe = *dedupe
+	// the registered language is shared, so the chosen preprocessor goes to a copy of it
+	lang := *choice.Language
+	if lang.NewPreprocessor != nil {
+		lang.Preprocessor = lang.NewPreprocessor(logger, language.PreprocessorOptions{MorphPath: *ruMorph, Offline: *offlineMode})
 	}
+	words := lexicon.New(&lang)
+	// suggestions are not needed until the first search so words are loaded in background
+	go loadLexicon(logger, words, strings.Split(*wordList, ","), fileNameList)
 	// enter target -> loop (enter word, select definition)
 	target := prompt.Input(
 		"Target: ",
-		targetCompleter(fileNameList),
+		targetCompleter(targetNameList),
 		prompt.OptionShowCompletionAtStart(),
 		prompt.OptionCompletionOnDown(),
 	)
 	tools.Exit()
-	shouldPadding := false
-	var out io.Writer
-	if target == "/dev/null" {
-		out = io.Discard
-	} else {
-		if _, err := os.Stat(target); err == nil {
-			logger.Debugln("target exist")
-			shouldPadding = true
-		} else if !os.IsNotExist(err) {
-			logger.Error(err)
-			return
-		}
+	format := targetFormat
+	if target != os.DevNull {
 		if filepath.Ext(target) == "" {
-			target += ".txt"
-		} else if filepath.Ext(target) != ".txt" {
-			fmt.Println("\n\033[31mplease enter .txt as file extension\033[0m")
-			return
+			if len(format) == 0 {
+				format = output.Quizlet
+			}
+			target += output.Extension(format)
+		} else if len(format) == 0 {
+			if format, err = output.FormatOf(target); err != nil {
+				fmt.Printf("\n\033[31mplease enter one of %s as file extension\033[0m\n", strings.Join(output.Extensions(), ", "))
+				return
+			}
 		}
-		outFile, err := os.OpenFile(filepath.Clean(target), os.O_CREATE|os.O_RDWR|os.O_APPEND|os.O_SYNC, 0600)
+	}
+	out, err := output.Open(target, format)
+	if errors.Is(err, output.ErrCorrupt) {
+		fmt.Printf("\n\033[31m%s\033[0m\n", err)
+		return
+	} else if err != nil {
+		logger.Errorln("Fail to open output file", err)
+		return
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			logger.Errorf("Error closing file: %s\n", err)
+		}
+	}()
+	if format == output.Quizlet && !slices.Contains(offline.Targets, target) {
+		offline.Targets = append(offline.Targets, target)
+	}
+	logger.Infof("choice lang: [%s] %s with source: %s", choice.Language.ID, choice.Display, target)
+	m := initialModel(logger, dict, out, &lang, target)
+	m.SearchTimeout = *searchTimeout
+	m.Lexicon = words
+	m.HeadwordTyped = *headword == "typed"
+	if target != os.DevNull {
+		saved, err := output.Read(target, format)
 		if err != nil {
-			logger.Errorln("Fail to open output file", err)
-			return
+			logger.Warnln("Fail to index saved words of target:", err)
+		} else {
+			m.Saved = output.NewIndex(saved)
+			logger.Infoln("target has", m.Saved.Len(), "saved words")
 		}
-		defer func() {
-			if err := outFile.Close(); err != nil {
-				logger.Errorf("Error closing file: %s\n", err)
-			}
-		}()
-		if shouldPadding {
-			lastByte := make([]byte, 2)
-			end, err := outFile.Seek(0, io.SeekEnd)
-			if err != nil {
-				logger.Error(err)
-			}
-			if end > 1 {
-				n, err := outFile.ReadAt(lastByte, end-2)
-				if n != 2 {
-					fmt.Printf("\n\033[31mfile corrupt\033[0m")
-					return
-				} else if err != nil {
-					logger.Error(err)
-					return
-				}
-				if string(lastByte) != "\n\n" {
-					if _, err := outFile.WriteString("\n\n"); err != nil {
-						return
-					}
-				}
-			}
+	}
+	if len(*historyPath) != 0 {
+		if m.History, err = history.New(*historyPath, *historySize); err != nil {
+			logger.Warnln("Fail to load history:", err)
 		}
-		out = outFile
 	}
-	logger.Infof("choice lang: [%d] with source: %s", choice.Dictionary, target)
-	p := tea.NewProgram(initialModel(logger, lemmatizer, dict, out, choice.Language, target), tea.WithAltScreen())
-	// p := tea.NewProgram(initialModel(logger, lemmatizer, dict, out, language, target))
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	// p := tea.NewProgram(initialModel(logger, dict, out, language, target))
 
 	if m, err := p.Run(); err != nil {
 		logger.Fatal(err)
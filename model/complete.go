@@ -0,0 +1,51 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// completionLimit is the max number of completions under the input
+	completionLimit = 5
+	// completionMinLength is the shortest input to complete, shorter ones match too many words
+	completionMinLength = 2
+)
+
+// updateCompletions finds known words starting with the input
+func (m Dictionary) updateCompletions() Dictionary {
+	m.completions = nil
+	m.completeCursor = 0
+	input := strings.TrimLeft(m.SearchWord.Value(), " ")
+	if m.Lexicon == nil || utf8.RuneCountInString(input) < completionMinLength {
+		return m
+	}
+	m.completions = m.Lexicon.Complete(input, completionLimit)
+	return m
+}
+
+// acceptCompletion replaces input by the chosen completion and completes it further
+func (m Dictionary) acceptCompletion() Dictionary {
+	if len(m.completions) == 0 {
+		return m
+	}
+	m.SearchWord.SetValue(m.completions[m.completeCursor])
+	m.SearchWord.CursorEnd()
+	return m.updateCompletions()
+}
+
+func (m Dictionary) completionView() string {
+	if len(m.completions) == 0 {
+		return ""
+	}
+	items := make([]string, 0, len(m.completions))
+	for idx, completion := range m.completions {
+		if idx == m.completeCursor {
+			items = append(items, fmt.Sprintf("\033[92m%s\033[0m", completion))
+		} else {
+			items = append(items, completion)
+		}
+	}
+	return fmt.Sprintf("\n      %s  \033[2m[Tab to complete, Ctrl+N/Ctrl+P to choose]\033[0m", strings.Join(items, "  "))
+}
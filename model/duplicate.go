@@ -0,0 +1,137 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+	"github.com/s8508235/tui-dictionary/pkg/output"
+)
+
+type saveMode int
+
+const (
+	// saveAppend writes the word again after the saved one
+	saveAppend saveMode = iota
+	// saveMerge keeps saved definitions and adds new ones
+	saveMerge
+	// saveReplace drops saved definitions
+	saveReplace
+)
+
+// savedDefinitionLimit is the max number of saved definitions shown in selection
+const savedDefinitionLimit = 3
+
+// savedEntries are entries of the word to write already in target
+func (m Dictionary) savedEntries() []output.Entry {
+	if m.Saved == nil {
+		return nil
+	}
+	return m.Saved.Get(m.headword())
+}
+
+// save writes definitions of the word to target by mode and goes back to search
+func (m Dictionary) save(mode saveMode, definitions []entity.Definition) (tea.Model, tea.Cmd) {
+	entry := output.Entry{
+		Word:        m.headword(),
+		Language:    m.Language.ID,
+		Definitions: definitions,
+		SavedAt:     time.Now(),
+	}
+	if mode == saveMerge {
+		entry.Definitions = output.MergeDefinitions(m.savedEntries(), definitions)
+	}
+	var err error
+	if len(m.replacing) != 0 && mode == saveReplace {
+		// the word searched again from target browser keeps its place
+		m.targetEntries = replaceEntry(m.targetEntries, entry)
+		err = m.Out.Rewrite(m.targetEntries)
+	} else {
+		err = writeOutput(m.Logger, m.Out, entry, mode != saveAppend)
+	}
+	if err != nil {
+		m.err = fmt.Errorf("fail to write output file: %w", err)
+		return m, tea.Quit
+	}
+	if m.Saved != nil {
+		if mode == saveAppend {
+			m.Saved.Add(entry)
+		} else {
+			m.Saved.Set(entry)
+		}
+	}
+	if m.Lexicon != nil {
+		m.Lexicon.AddSaved(m.headword())
+	}
+	m.recordHistory(true, definitions)
+	m.flushing = nil
+	if len(m.replacing) != 0 {
+		// back to target browser where the word is searched again
+		m = m.backToSearch()
+		m, cmd := m.openTarget()
+		return m.targetFocus(entry.Word), cmd
+	}
+	// back to search state
+	return m.backToSearch(), textinput.Blink
+}
+
+func (m Dictionary) updateDuplicate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch language.ShortcutKey(msg.String()) {
+	case "m", "M":
+		return m.save(saveMerge, m.flushing)
+	case "r", "R":
+		return m.save(saveReplace, m.flushing)
+	case "a", "A":
+		if output.AppendsDuplicates(m.Out) {
+			return m.save(saveAppend, m.flushing)
+		}
+	case "q", "Q", "esc":
+		// back to select def state
+		m.flushing = nil
+		m.state = dictionarySelectDef
+	case "ctrl+c", "ctrl+C":
+		m.cancelSearch()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// savedView warns that the word to write is already in target
+func (m Dictionary) savedView() string {
+	saved := m.savedEntries()
+	if len(saved) == 0 {
+		return ""
+	}
+	definitions := output.MergeDefinitions(saved, nil)
+	s := fmt.Sprintf("\033[33m%s is already saved in target:\033[0m\n", m.headword())
+	for idx, d := range definitions {
+		if idx == savedDefinitionLimit {
+			s += fmt.Sprintf("  ... and %d more\n", len(definitions)-savedDefinitionLimit)
+			break
+		}
+		s += fmt.Sprintf("  - %s\n", output.FormatDefinition(d))
+	}
+	return s
+}
+
+func (m Dictionary) duplicateView() string {
+	s := fmt.Sprintf("Target: %s\n", m.Target)
+	s += fmt.Sprintf("\033[92m%s\033[0m is already saved in target with:\n", m.headword())
+	for idx, d := range output.MergeDefinitions(m.savedEntries(), nil) {
+		s += fmt.Sprintf("%3d. %s\n", idx+1, output.FormatDefinition(d))
+	}
+	s += "\nSaving now:\n"
+	for idx, d := range m.flushing {
+		s += fmt.Sprintf("%3d. %s\n", idx+1, output.FormatDefinition(d))
+	}
+	s += "\nPress m to merge them, r to replace saved definitions"
+	if output.AppendsDuplicates(m.Out) {
+		s += ", a to append the word again"
+	}
+	s += "\n"
+	s += "q to go back to definitions, Ctrl + c to quit"
+	return s
+}
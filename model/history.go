@@ -0,0 +1,167 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/history"
+	"github.com/s8508235/tui-dictionary/pkg/output"
+)
+
+// recordHistory keeps searched word with the sources of its definitions
+func (m Dictionary) recordHistory(saved bool, definitions []entity.Definition) {
+	if m.History == nil || len(m.searchWord) == 0 {
+		return
+	}
+	sources := make([]string, 0)
+	for _, d := range definitions {
+		if len(d.Source) != 0 && !containsString(sources, d.Source) {
+			sources = append(sources, d.Source)
+		}
+	}
+	entry := history.Entry{
+		Word:     m.searchWord,
+		Language: m.Language.ID,
+		Sources:  sources,
+		Saved:    saved,
+	}
+	if err := m.History.Add(entry); err != nil {
+		m.Logger.Warnln("Fail to write history:", err)
+	}
+}
+
+// recordLookup counts a search of word in target if it keeps lookup counts
+func (m Dictionary) recordLookup() {
+	recorder, ok := m.Out.(output.LookupRecorder)
+	if !ok {
+		return
+	}
+	if err := recorder.RecordLookup(m.searchWord, m.Language.ID); err != nil {
+		m.Logger.Warnln("Fail to record lookup:", err)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// recall walks earlier inputs, older for up and newer for down, back to the draft at the end
+func (m Dictionary) recall(older bool) Dictionary {
+	if m.History == nil {
+		return m
+	}
+	if m.recallIndex == 0 {
+		if !older {
+			return m
+		}
+		m.recalls = m.History.Recent(m.Language.ID)
+		m.recallDraft = m.SearchWord.Value()
+	}
+	next := m.recallIndex - 1
+	if older {
+		next = m.recallIndex + 1
+	}
+	if next > len(m.recalls) {
+		return m
+	}
+	m.recallIndex = next
+	if next == 0 {
+		m.SearchWord.SetValue(m.recallDraft)
+	} else {
+		m.SearchWord.SetValue(m.recalls[next-1].Word)
+	}
+	m.SearchWord.CursorEnd()
+	m.completions = nil
+	return m
+}
+
+// openHistory shows the history browser
+func (m Dictionary) openHistory() (Dictionary, tea.Cmd) {
+	if m.History == nil {
+		m.warnMsg = "history is disabled"
+		return m, nil
+	}
+	m.historyFilter = textinput.New()
+	m.historyFilter.Placeholder = "filter by word or source"
+	m.historyFilter.Focus()
+	m.historyEntries = m.History.Recent(m.Language.ID)
+	m.historyCursor = 0
+	m.SearchWord.Blur()
+	m.state = dictionaryHistory
+	return m, textinput.Blink
+}
+
+func (m Dictionary) updateHistory(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyUp:
+			if len(m.historyEntries) != 0 {
+				m.historyCursor = (m.historyCursor - 1 + len(m.historyEntries)) % len(m.historyEntries)
+			}
+			return m, nil
+		case tea.KeyDown:
+			if len(m.historyEntries) != 0 {
+				m.historyCursor = (m.historyCursor + 1) % len(m.historyEntries)
+			}
+			return m, nil
+		case tea.KeyEnter:
+			if len(m.historyEntries) == 0 {
+				return m, nil
+			}
+			word := m.historyEntries[m.historyCursor].Word
+			m.SearchWord.SetValue(word)
+			return m.search(word, false)
+		case tea.KeyEsc:
+			// back to search state
+			m.state = dictionarySearchStart
+			m.SearchWord.Focus()
+			return m, textinput.Blink
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		}
+	}
+	var cmd tea.Cmd
+	before := m.historyFilter.Value()
+	m.historyFilter, cmd = m.historyFilter.Update(msg)
+	if m.historyFilter.Value() != before {
+		m.historyEntries = m.History.Filter(m.Language.ID, strings.TrimSpace(m.historyFilter.Value()))
+		m.historyCursor = 0
+	}
+	return m, cmd
+}
+
+func (m Dictionary) historyView() string {
+	header := fmt.Sprintf("Target: %s\nSearch history: %s\n\n", m.Target, m.historyFilter.View())
+	footer := "\nPress up/down to choose, enter to search again\nType to filter, Esc to go back, Ctrl + c to quit."
+	if len(m.historyEntries) == 0 {
+		return header + "no lookup yet\n" + footer
+	}
+	pageLineCount := m.height - strings.Count(header, "\n") - strings.Count(footer, "\n") - 1
+	if pageLineCount < 1 {
+		pageLineCount = len(m.historyEntries)
+	}
+	start := m.historyCursor / pageLineCount * pageLineCount
+	var content string
+	for idx := start; idx < len(m.historyEntries) && idx < start+pageLineCount; idx++ {
+		entry := m.historyEntries[idx]
+		cursor := " "
+		if idx == m.historyCursor {
+			cursor = ">"
+		}
+		saved := "     "
+		if entry.Saved {
+			saved = "saved"
+		}
+		content += fmt.Sprintf("%s %s  \033[33m%s\033[0m  \033[92m%s\033[0m  \033[2m%s\033[0m\n",
+			cursor, entry.SearchedAt.Format("2006-01-02 15:04"), saved, entry.Word, strings.Join(entry.Sources, ", "))
+	}
+	return header + content + footer
+}
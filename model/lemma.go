@@ -0,0 +1,106 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/output"
+)
+
+// preprocess gives base forms of input word by its language, the word as typed if preprocessor fails
+func (m *Dictionary) preprocess(inputWord string) []string {
+	preprocessor := m.Language.Preprocessor
+	if preprocessor == nil {
+		return []string{inputWord}
+	}
+	ctx := context.Background()
+	if m.SearchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.SearchTimeout)
+		defer cancel()
+	}
+	lemmas, err := preprocessor.Lemmas(ctx, inputWord)
+	if err != nil || len(lemmas) == 0 {
+		m.Logger.Warnln("Fail to preprocess", inputWord, err)
+		m.warnMsg = fmt.Sprintf("no base form found for %s, searching as typed", inputWord)
+		return []string{inputWord}
+	}
+	return lemmas
+}
+
+// nextLemma searches the next base form of input word
+func (m Dictionary) nextLemma() (tea.Model, tea.Cmd) {
+	if len(m.lemmas) < 2 {
+		m.warnMsg = fmt.Sprintf("%s has no other lemma", m.inputWord)
+		return m, nil
+	}
+	m.lemmaIndex = (m.lemmaIndex + 1) % len(m.lemmas)
+	return m.searchAs(m.lemmas[m.lemmaIndex])
+}
+
+// searchAs drops the current search and searches word for the same input
+func (m Dictionary) searchAs(word string) (tea.Model, tea.Cmd) {
+	if len(word) == 0 || word == m.searchWord {
+		return m, nil
+	}
+	m.cancelSearch()
+	m.pending = nil
+	m.results = nil
+	m.failures = nil
+	m.warnMsg = ""
+	m.Selected = make(map[int]struct{})
+	m.Choices = make([]entity.Definition, 0)
+	m.cursor = 0
+	m.searchWord = word
+	m.Logger.Infoln("going to search", m.searchWord, "for", m.inputWord)
+	m.state = dictionarySearching
+	return m.startSearch()
+}
+
+// lemmaNote tells which input word the searched word comes from
+func (m Dictionary) lemmaNote() string {
+	if len(m.inputWord) == 0 || output.SameWord(m.inputWord, m.searchWord) {
+		return ""
+	}
+	return fmt.Sprintf(" (lemma of %s)", m.inputWord)
+}
+
+// lemmaView shows the searched word and other base forms to choose
+func (m Dictionary) lemmaView() string {
+	s := fmt.Sprintf("Searching \033[92m%s\033[0m%s\n", m.searchWord, m.lemmaNote())
+	others := make([]string, 0, len(m.lemmas))
+	for _, lemma := range m.lemmas {
+		if lemma != m.searchWord {
+			others = append(others, lemma)
+		}
+	}
+	if len(others) != 0 {
+		s += fmt.Sprintf("Other lemmas: %s [press l to switch]\n", strings.Join(others, ", "))
+	}
+	if len(m.lemmaNote()) != 0 {
+		s += "Press r to search as typed\n"
+	}
+	return s
+}
+
+// headword is the word written to target
+func (m Dictionary) headword() string {
+	if m.HeadwordTyped && len(m.inputWord) != 0 {
+		return m.inputWord
+	}
+	return m.searchWord
+}
+
+func (m Dictionary) headwordView() string {
+	if output.SameWord(m.inputWord, m.searchWord) {
+		return ""
+	}
+	other := m.inputWord
+	if m.HeadwordTyped {
+		other = m.searchWord
+	}
+	return fmt.Sprintf("Write as \033[92m%s\033[0m [press h to write %s instead]\n", m.headword(), other)
+}
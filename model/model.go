@@ -1,54 +1,109 @@
 package model
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"io"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/aaaton/golem/v4"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/s8508235/tui-dictionary/pkg/dictionary"
 	"github.com/s8508235/tui-dictionary/pkg/entity"
-	"github.com/s8508235/tui-dictionary/pkg/tools"
+	"github.com/s8508235/tui-dictionary/pkg/history"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+	"github.com/s8508235/tui-dictionary/pkg/lexicon"
+	"github.com/s8508235/tui-dictionary/pkg/output"
 	"github.com/sirupsen/logrus"
 )
 
 type dictionaryState int
-type dictionaryResult []string
 
 const (
 	dictionarySearchStart dictionaryState = iota
 	dictionarySearching
 	dictionarySelectDef
 	dictionaryDefDetail
+	dictionarySuggest
+	dictionaryHistory
+	dictionaryDuplicate
+	dictionaryTarget
 )
 
 type Dictionary struct {
-	Language   entity.DictionaryLanguage
-	Target     string
-	SearchWord textinput.Model
-	Spinner    spinner.Model
+	Language *language.Language
+	Target   string
+	// SearchTimeout bounds a single search, zero means no limit
+	SearchTimeout time.Duration
+	SearchWord    textinput.Model
+	Spinner       spinner.Model
 	// selection
-	Choices  []string         // items on the to-do list
-	cursor   int              // which to-do list item our cursor is pointing at
-	Selected map[int]struct{} // which to-do items are selected
+	Choices  []entity.Definition // items on the to-do list
+	cursor   int                 // which to-do list item our cursor is pointing at
+	Selected map[int]struct{}    // which to-do items are selected
 	// internal
 	searchWord string
-	warnMsg    string
-	state      dictionaryState
-	err        error
-	height     int
-	width      int
+	inputWord  string
+	// lemmas are base forms of input word, lemmaIndex is the one searched
+	lemmas     []string
+	lemmaIndex int
+	searchID   int
+	cancel     context.CancelFunc
+	// bypassCache refreshes cached definitions in this search
+	bypassCache bool
+	pending     []string // dictionaries not answered yet in a streaming search
+	results     []dictionary.SourceResult
+	failures    []string
+	// suggestions are known words close to a word without definition
+	suggestions   []string
+	suggestCursor int
+	// completions are known words starting with the input
+	completions    []string
+	completeCursor int
+	// recalls are earlier inputs walked by up and down, recallIndex 0 means the draft being typed
+	recalls     []history.Entry
+	recallIndex int
+	recallDraft string
+	// history browser
+	historyFilter  textinput.Model
+	historyEntries []history.Entry
+	historyCursor  int
+	// flushing are definitions waiting for the choice of how to save a word already in target
+	flushing []entity.Definition
+	// target browser, targetVisible are indexes of entries matching filter and targetCursor is in them
+	targetFilter    textinput.Model
+	targetEntries   []output.Entry
+	targetVisible   []int
+	targetCursor    int
+	targetOpen      bool // definitions of the entry under cursor are shown
+	targetDefCursor int
+	targetEdit      textinput.Model
+	targetEditing   bool
+	targetDeleting  bool // delete is pressed once and waits for another press
+	// replacing is the word searched again from target browser, its flushed definitions replace saved ones
+	replacing string
+	warnMsg   string
+	state     dictionaryState
+	err       error
+	height    int
+	width     int
 	// dependencies
 	Logger     *logrus.Logger
-	Out        io.Writer
-	Lemmatizer *golem.Lemmatizer
+	Out        output.Writer
 	Dictionary dictionary.Interface
+	// HeadwordTyped writes the word as typed instead of the searched lemma
+	HeadwordTyped bool
+	// Lexicon gives spelling suggestions and completions, nil means neither
+	Lexicon *lexicon.Lexicon
+	// History records lookups, nil means no history
+	History *history.History
+	// Saved indexes words in target to warn about saving a word again, nil means no warning
+	Saved *output.Index
 }
 
 func (m Dictionary) Init() tea.Cmd {
@@ -62,6 +117,18 @@ func (m Dictionary) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		m.width = msg.Width
 		return m, nil
+	case sourceResultMsg:
+		return m.handleSourceResult(msg)
+	case streamDoneMsg:
+		return m.handleStreamDone(msg)
+	case spinner.TickMsg:
+		// keep spinning while any dictionary is still searching
+		if m.state != dictionarySearching && len(m.pending) == 0 {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.Spinner, cmd = m.Spinner.Update(msg)
+		return m, cmd
 	}
 	switch m.state {
 	case dictionarySearchStart:
@@ -69,43 +136,33 @@ func (m Dictionary) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			switch msg.Type {
-			case tea.KeyEnter:
-				inputWord := strings.TrimSpace(m.SearchWord.Value())
-				err := tools.WordValidate(inputWord, m.Language)
-				switch err {
-				case nil:
-				case tools.ErrWord:
-					if len(inputWord) != 0 {
-						m.warnMsg = fmt.Sprintf("wrong format of input: %s", inputWord)
-					} else {
-						m.warnMsg = "empty input"
+			case tea.KeyEnter, tea.KeyCtrlR:
+				// ctrl+r searches again instead of using cached definitions
+				m.bypassCache = msg.Type == tea.KeyCtrlR
+				m.completions = nil
+				return m.search(strings.TrimSpace(m.SearchWord.Value()), false)
+			case tea.KeyCtrlT:
+				// search the word as typed without lemmatization
+				m.bypassCache = false
+				m.completions = nil
+				return m.search(strings.TrimSpace(m.SearchWord.Value()), true)
+			case tea.KeyTab:
+				return m.acceptCompletion(), nil
+			case tea.KeyUp, tea.KeyDown:
+				return m.recall(msg.Type == tea.KeyUp), nil
+			case tea.KeyCtrlO:
+				return m.openHistory()
+			case tea.KeyCtrlB:
+				return m.openTarget()
+			case tea.KeyCtrlN, tea.KeyCtrlP:
+				if len(m.completions) != 0 {
+					step := 1
+					if msg.Type == tea.KeyCtrlP {
+						step = len(m.completions) - 1
 					}
-					m.SearchWord.Reset()
-					m.SearchWord.Focus()
-					return m, textinput.Blink
-				default:
-					m.err = fmt.Errorf("fail to ask: %w", err)
-					return m, tea.Quit
+					m.completeCursor = (m.completeCursor + step) % len(m.completions)
 				}
-				switch m.Language {
-				case entity.English:
-					m.searchWord = m.Lemmatizer.Lemma(inputWord)
-				case entity.Russian:
-					m.searchWord, err = tools.RussianPreprocess(inputWord)
-					if err != nil {
-						m.err = err
-						return m, tea.Quit
-					}
-				default:
-					m.err = entity.ErrUnknownLanguage
-					return m, tea.Quit
-				}
-				m.warnMsg = ""
-				m.Logger.Infoln("going to search", m.searchWord)
-				// go to selectDef state
-				m.state = dictionarySearching
-				m.SearchWord.Blur()
-				return m, tea.Batch(m.Spinner.Tick, m.wordSearch())
+				return m, nil
 			case tea.KeyCtrlC, tea.KeyEsc:
 				return m, tea.Quit
 			}
@@ -114,77 +171,112 @@ func (m Dictionary) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.err = msg
 			return m, tea.Quit
 		}
+		before := m.SearchWord.Value()
 		m.SearchWord, cmd = m.SearchWord.Update(msg)
+		if m.SearchWord.Value() != before {
+			m.recallIndex = 0
+			m = m.updateCompletions()
+		}
 		return m, cmd
 	case dictionarySearching:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.String() {
-			case "q", "Q", "й", "Й":
+			switch language.ShortcutKey(msg.String()) {
+			case "q", "Q":
 				// back to search state
 				return m.backToSearch(), textinput.Blink
+			case "l", "L":
+				return m.nextLemma()
+			case "r", "R":
+				return m.searchAs(m.inputWord)
 			case "ctrl+c", "ctrl+C":
+				m.cancelSearch()
 				return m, tea.Quit
 			}
 		case dictionaryResult:
-			m.Choices = []string(msg)
-			m.cursor = 0
-			m.state = dictionarySelectDef
-			return m, nil
-		case error:
-			if !errors.Is(msg, dictionary.ErrorNoDef) {
-				m.err = msg
-				return m, tea.Quit
+			if msg.searchID != m.searchID {
+				// result of a cancelled search
+				return m, nil
+			}
+			m.cancelSearch()
+			var partial *dictionary.PartialError
+			switch {
+			case msg.err == nil:
+			case errors.As(msg.err, &partial):
+				// keep the definitions from dictionaries that answered
+				m.warnMsg = partial.Error()
+			case errors.Is(msg.err, dictionary.ErrorNoDef):
+				m.warnMsg = fmt.Sprintf("%s for %s", dictionary.ErrorNoDef.Error(), m.searchWord)
+			case errors.Is(msg.err, context.DeadlineExceeded):
+				m.warnMsg = fmt.Sprintf("search for %s timed out", m.searchWord)
+			default:
+				m.Logger.Errorln("search failed:", msg.err)
+				m.warnMsg = fmt.Sprintf("search for %s failed: %s", m.searchWord, msg.err)
+			}
+			if len(msg.definitions) != 0 {
+				m.Choices = msg.definitions
+				m.cursor = 0
+				m.state = dictionarySelectDef
+				return m, nil
+			}
+			if errors.Is(msg.err, dictionary.ErrorNoDef) {
+				return m.suggestAlternatives()
 			}
-			m.warnMsg = fmt.Sprintf("%s for %s", dictionary.ErrorNoDef.Error(), m.searchWord)
 			m.SearchWord.Reset()
 			m.SearchWord.Focus()
 			m.state = dictionarySearchStart
 			return m, textinput.Blink
-		default:
-			var cmd tea.Cmd
-			m.Spinner, cmd = m.Spinner.Update(msg)
-			return m, cmd
 		}
 	case dictionarySelectDef:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.String() {
-			case "c", "C", "с", "С":
+			switch language.ShortcutKey(msg.String()) {
+			case "c", "C":
 				m.warnMsg = ""
 				return m, nil
-			case "f", "F", "ctrl+s", "ctrl+S", "а", "А":
+			case "f", "F", "ctrl+s", "ctrl+S":
 				if len(m.Selected) == 0 {
 					m.warnMsg = "Please at least select one definition"
 					return m, nil
 				}
-				flushed := make([]string, 0, len(m.Selected))
-				for key := range m.Selected {
+				flushed := make([]entity.Definition, 0, len(m.Selected))
+				for _, key := range slices.Sorted(maps.Keys(m.Selected)) {
 					flushed = append(flushed, m.Choices[key])
 				}
-				if err := writeOutput(m.Logger, m.Out, m.searchWord, flushed); err != nil {
-					m.err = fmt.Errorf("fail to write output file: %w", err)
-					return m, tea.Quit
+				if len(m.replacing) != 0 && output.SameWord(m.replacing, m.headword()) {
+					return m.save(saveReplace, flushed)
 				}
-				// back to search state
-				return m.backToSearch(), textinput.Blink
+				if len(m.savedEntries()) != 0 {
+					m.flushing = flushed
+					m.state = dictionaryDuplicate
+					return m, nil
+				}
+				return m.save(saveAppend, flushed)
 			// These keys should exit the program.
 			case "ctrl+c", "ctrl+C":
+				m.cancelSearch()
 				return m, tea.Quit
-			case "up", "w", "W", "ц", "Ц":
+			case "up", "w", "W":
 				m.cursor = (m.cursor - 1 + len(m.Choices)) % len(m.Choices)
-			case "down", "s", "S", "ы", "Ы":
+			case "down", "s", "S":
 				m.cursor = (m.cursor + 1 + len(m.Choices)) % len(m.Choices)
-			case "enter", " ", "x", "X", "ч", "Ч":
+			case "enter", " ", "x", "X":
 				_, ok := m.Selected[m.cursor]
 				if ok {
 					delete(m.Selected, m.cursor)
 				} else {
 					m.Selected[m.cursor] = struct{}{}
 				}
-			case "q", "Q", "й", "Й":
+			case "q", "Q":
+				m.recordHistory(false, m.Choices)
 				// back to search state
 				return m.backToSearch(), textinput.Blink
+			case "l", "L":
+				return m.nextLemma()
+			case "r", "R":
+				return m.searchAs(m.inputWord)
+			case "h", "H":
+				m.HeadwordTyped = !m.HeadwordTyped
 			case "tab":
 				m.state = dictionaryDefDetail
 			}
@@ -193,8 +285,8 @@ func (m Dictionary) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case dictionaryDefDetail:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.String() {
-			case "enter", " ", "x", "X", "ч", "Ч":
+			switch language.ShortcutKey(msg.String()) {
+			case "enter", " ", "x", "X":
 				_, ok := m.Selected[m.cursor]
 				if ok {
 					delete(m.Selected, m.cursor)
@@ -202,14 +294,27 @@ func (m Dictionary) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.Selected[m.cursor] = struct{}{}
 				}
 				m.state = dictionarySelectDef
-			case "q", "Q", "й", "Й":
+			case "q", "Q":
 				// back to select def state
 				m.state = dictionarySelectDef
 			case "ctrl+c", "ctrl+C":
+				m.cancelSearch()
 				return m, tea.Quit
 			}
 			return m, nil
 		}
+	case dictionarySuggest:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return m.updateSuggest(msg)
+		}
+	case dictionaryHistory:
+		return m.updateHistory(msg)
+	case dictionaryDuplicate:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return m.updateDuplicate(msg)
+		}
+	case dictionaryTarget:
+		return m.updateTarget(msg)
 	default:
 		m.err = errors.New("unreachable")
 		return m, tea.Quit
@@ -222,7 +327,8 @@ func (m Dictionary) View() string {
 	switch m.state {
 	case dictionarySearchStart:
 		var s string
-		s = fmt.Sprintf("Target: %s\nWord: %s [Press enter to search, Ctrl+C or Esc to exit]", m.Target, m.SearchWord.View())
+		s = fmt.Sprintf("Target: %s\nWord: %s [Press enter to search, Ctrl+R to search without cache, Ctrl+T to search as typed, Ctrl+O for history, Ctrl+B to edit target, Ctrl+C or Esc to exit]", m.Target, m.SearchWord.View())
+		s += m.completionView()
 		if len(m.warnMsg) != 0 {
 			s += fmt.Sprintf("\n\033[31m%s\033[0m\n", m.warnMsg)
 		}
@@ -230,21 +336,38 @@ func (m Dictionary) View() string {
 	case dictionarySearching:
 		var s string
 		s = fmt.Sprintf("Target: %s\n", m.Target)
+		s += m.lemmaView()
 		if len(m.warnMsg) != 0 {
 			s += fmt.Sprintf("\n\033[31m%s\033[0m\n", m.warnMsg)
 		}
-		s += fmt.Sprintf("%s\nEnter q to cancel or Ctrl+C to exit", m.Spinner.View())
+		if len(m.pending) != 0 {
+			for _, source := range m.pending {
+				s += fmt.Sprintf("%s %s\n", m.Spinner.View(), source)
+			}
+			s += "Enter q to cancel or Ctrl+C to exit"
+		} else {
+			s += fmt.Sprintf("%s\nEnter q to cancel or Ctrl+C to exit", m.Spinner.View())
+		}
 		return s
 	case dictionarySelectDef:
 		header := fmt.Sprintf("Target: %s\n", m.Target)
-		header += fmt.Sprintf("There are \033[92m%d\033[0m definitions, please choose one or more definitions for \033[92m%s\033[0m:\n\n", len(m.Choices), m.searchWord)
+		header += fmt.Sprintf("There are \033[92m%d\033[0m definitions, please choose one or more definitions for \033[92m%s\033[0m%s:\n", len(m.Choices), m.searchWord, m.lemmaNote())
+		header += m.headwordView() + m.savedView() + "\n"
+		if len(m.pending) != 0 {
+			header += "Still searching:"
+			for _, source := range m.pending {
+				header += fmt.Sprintf(" %s %s", m.Spinner.View(), source)
+			}
+			header += "\n\n"
+		}
 		if len(m.warnMsg) != 0 {
 			header += fmt.Sprintf("\033[31m%s\033[0m\n\n", m.warnMsg)
 		}
-		footer := "\nPress space, enter or x to select\nPress q to skip\n"
+		footer := "\nPress space, enter or x to select\nPress q to skip, l to search another lemma, r to search as typed\n"
 		footer += "Press f or Ctrl + s to flush\nPress Ctrl + c to quit."
 		remainHeight := lipgloss.Height(header) + lipgloss.Height(footer)
-		pageLineCount := m.height - remainHeight + 1
+		// every source group may take one more line for its header
+		pageLineCount := m.height - remainHeight + 1 - sourceGroupCount(m.Choices)
 		if pageLineCount < 1 {
 			return "too small to show content"
 		}
@@ -260,6 +383,11 @@ func (m Dictionary) View() string {
 			if currentPage*pageLineCount > i || (currentPage+1)*pageLineCount <= i {
 				continue
 			}
+			// section header at the start of each source and the top of each page
+			if len(choice.Source) != 0 && (i == currentPage*pageLineCount || groupLabel(m.Choices[i-1]) != groupLabel(choice)) {
+				content += fmt.Sprintf("\033[36m── %s ──\033[0m\n", groupLabel(choice))
+				currentHeight++
+			}
 			// Is the cursor pointing at this choice?
 			cursor := " " // no cursor
 			if m.cursor == i {
@@ -271,7 +399,7 @@ func (m Dictionary) View() string {
 				checked = "x" // selected!
 			}
 			// Render the row
-			line := fmt.Sprintf("%s %2d [%s] %s\n", cursor, i+1, checked, choice)
+			line := fmt.Sprintf("%s %2d [%s] %s%s\n", cursor, i+1, checked, choice, mergedTag(choice))
 			// longer than terminal
 			if width := lipgloss.Width(line); width > m.width+1 {
 				// since we replace all \s with space when search
@@ -292,7 +420,7 @@ func (m Dictionary) View() string {
 				// 	}
 				// 	curr += m.width
 				// }
-				content += fmt.Sprintf("%s...\n", line[:m.width-4])
+				content += fmt.Sprintf("%s...\033[0m\n", line[:m.width-4])
 				currentHeight++
 				if currentHeight > m.height {
 					break
@@ -310,19 +438,65 @@ func (m Dictionary) View() string {
 	case dictionaryDefDetail:
 		header := fmt.Sprintf("Target: %s\n", m.Target)
 		header += fmt.Sprintf("The %d definition for \033[92m%s\033[0m:\n\n", m.cursor+1, m.searchWord)
-		content := fmt.Sprintf("\t%s\n", m.Choices[m.cursor])
+		content := definitionDetail(m.Choices[m.cursor])
 		footer := "\033[38:2:255:165:0m[end of detailed definition]\033[0m\n"
 		footer += "Press space, enter or x to select and quit detailed view\nq to quit without changes\n"
 		return fmt.Sprintf("%s%s%s", header, content, footer)
+	case dictionarySuggest:
+		return m.suggestView()
+	case dictionaryHistory:
+		return m.historyView()
+	case dictionaryDuplicate:
+		return m.duplicateView()
+	case dictionaryTarget:
+		return m.targetView()
 	default:
 		return "some went wrong"
 	}
 }
 
+// search validates and preprocesses input word before searching dictionary, raw skips lemmatization
+func (m Dictionary) search(inputWord string, raw bool) (tea.Model, tea.Cmd) {
+	if err := m.Language.Validate(inputWord); err != nil || len(inputWord) == 0 {
+		if len(inputWord) != 0 {
+			m.warnMsg = fmt.Sprintf("wrong format of input: %s (%s)", inputWord, err)
+		} else {
+			m.warnMsg = "empty input"
+		}
+		m.SearchWord.Reset()
+		m.SearchWord.Focus()
+		return m, textinput.Blink
+	}
+	m.inputWord = inputWord
+	m.lemmaIndex = 0
+	m.warnMsg = ""
+	if raw {
+		m.lemmas = []string{inputWord}
+	} else {
+		m.lemmas = m.preprocess(inputWord)
+	}
+	m.searchWord = m.lemmas[0]
+	m.Logger.Infoln("going to search", m.searchWord)
+	m.recordLookup()
+	// go to selectDef state
+	m.state = dictionarySearching
+	m.SearchWord.Blur()
+	return m.startSearch()
+}
+
 func (m Dictionary) backToSearch() Dictionary {
+	m.cancelSearch()
+	m.pending = nil
+	m.results = nil
+	m.failures = nil
+	m.suggestions = nil
+	m.completions = nil
+	m.recallIndex = 0
+	m.lemmas = nil
+	m.replacing = ""
 	m.warnMsg = ""
 	m.Selected = make(map[int]struct{})
-	m.Choices = make([]string, 0)
+	m.Choices = make([]entity.Definition, 0)
 	m.cursor = 0
 	m.state = dictionarySearchStart
 	m.SearchWord.Reset()
@@ -334,38 +508,76 @@ func (m Dictionary) GetError() error {
 	return m.err
 }
 
-func (m Dictionary) wordSearch() tea.Cmd {
-	return func() tea.Msg {
-		results, err := m.Dictionary.Search(m.searchWord)
-		if err != nil {
-			return err
+// mergedTag lists the other dictionaries folded into definition
+func mergedTag(d entity.Definition) string {
+	if len(d.MergedSources) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" \033[2m(+%s)\033[0m", strings.Join(d.MergedSources, ", "))
+}
+
+// groupLabel is the section header of definition in selection list
+func groupLabel(d entity.Definition) string {
+	if d.Offline {
+		return fmt.Sprintf("%s \033[33m[offline result]\033[36m", d.Source)
+	}
+	return d.Source
+}
+
+// sourceGroupCount counts the runs of the same source in definitions
+func sourceGroupCount(definitions []entity.Definition) int {
+	count := 0
+	for idx, d := range definitions {
+		if len(d.Source) != 0 && (idx == 0 || groupLabel(definitions[idx-1]) != groupLabel(d)) {
+			count++
 		}
-		return dictionaryResult(results)
 	}
+	return count
 }
 
-func writeOutput(logger *logrus.Logger, out io.Writer, searchWord string, definition []string) error {
-	var buf bytes.Buffer
-	if _, err := buf.WriteString(searchWord); err != nil {
-		logger.Errorln("Fail to write:", err)
-		return err
+// definitionDetail renders every field of definition for detailed view
+func definitionDetail(d entity.Definition) string {
+	var content string
+	if len(d.Source) != 0 {
+		content += fmt.Sprintf("\tsource: %s", d.Source)
+		if d.Sense > 0 {
+			content += fmt.Sprintf(" #%d", d.Sense)
+		}
+		content += "\n"
 	}
-	if _, err := buf.WriteRune('\t'); err != nil {
-		logger.Errorln("Fail to write:", err)
-		return err
+	if d.Offline {
+		content += "\t\033[33moffline result from saved lookups\033[0m\n"
 	}
-	if _, err := buf.WriteString(strings.Join(definition, ";")); err != nil {
-		logger.Errorln("Fail to write:", err)
-		return err
+	if len(d.MergedSources) != 0 {
+		content += fmt.Sprintf("\talso in: %s\n", strings.Join(d.MergedSources, ", "))
 	}
-	if _, err := buf.WriteRune('\n'); err != nil {
-		logger.Errorln("Fail to write:", err)
-		return err
+	if len(d.PartOfSpeech) != 0 {
+		content += fmt.Sprintf("\tpart of speech: %s\n", d.PartOfSpeech)
 	}
-	if _, err := out.Write(buf.Bytes()); err != nil {
+	if len(d.Labels) != 0 {
+		content += fmt.Sprintf("\tlabels: %s\n", strings.Join(d.Labels, ", "))
+	}
+	content += fmt.Sprintf("\t%s\n", d.Text)
+	for _, example := range d.Examples {
+		content += fmt.Sprintf("\t  e.g. %s\n", example)
+	}
+	return content
+}
+
+// writeOutput appends word with chosen definitions to target, replace drops the word saved before
+func writeOutput(logger *logrus.Logger, out output.Writer, entry output.Entry, replace bool) error {
+	write := out.Write
+	if replace {
+		write = out.Replace
+	}
+	if err := write(entry); err != nil {
 		logger.Errorln("Fail to write output file though:", err)
 		return err
 	}
-	logger.Infoln("word:", searchWord, "definition:", strings.Join(definition, ";"))
+	definition := make([]string, 0, len(entry.Definitions))
+	for _, d := range entry.Definitions {
+		definition = append(definition, output.FormatDefinition(d))
+	}
+	logger.Infoln("word:", entry.Word, "definition:", strings.Join(definition, ";"))
 	return nil
 }
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
+
+// dictionaryResult is sent back once a search is finished
+type dictionaryResult struct {
+	searchID    int
+	definitions []entity.Definition
+	err         error
+}
+
+// sourceResultMsg carries the answer of one dictionary in a streaming search
+type sourceResultMsg struct {
+	searchID int
+	result   dictionary.SourceResult
+	stream   <-chan dictionary.SourceResult
+}
+
+// streamDoneMsg is sent after every dictionary of a streaming search answered
+type streamDoneMsg struct {
+	searchID int
+}
+
+// startSearch runs the search with a cancellable context kept in model,
+// a dictionary.Streamer sends its results one dictionary at a time
+func (m Dictionary) startSearch() (Dictionary, tea.Cmd) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if m.SearchTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), m.SearchTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	if m.bypassCache {
+		ctx = dictionary.WithoutCache(ctx)
+	}
+	m.searchID++
+	m.cancel = cancel
+	m.failures = nil
+	m.results = nil
+	if streamer, ok := m.Dictionary.(dictionary.Streamer); ok {
+		m.pending = streamer.Sources()
+		stream := streamer.SearchStream(ctx, m.searchWord)
+		return m, tea.Batch(m.Spinner.Tick, waitForSource(m.searchID, stream))
+	}
+	return m, tea.Batch(m.Spinner.Tick, m.wordSearch(ctx, m.searchID, m.searchWord))
+}
+
+// cancelSearch stops the running search if any
+func (m *Dictionary) cancelSearch() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+func (m Dictionary) wordSearch(ctx context.Context, searchID int, word string) tea.Cmd {
+	return func() tea.Msg {
+		results, err := m.Dictionary.Search(ctx, word)
+		return dictionaryResult{
+			searchID:    searchID,
+			definitions: results,
+			err:         err,
+		}
+	}
+}
+
+func waitForSource(searchID int, stream <-chan dictionary.SourceResult) tea.Cmd {
+	return func() tea.Msg {
+		result, ok := <-stream
+		if !ok {
+			return streamDoneMsg{searchID: searchID}
+		}
+		return sourceResultMsg{
+			searchID: searchID,
+			result:   result,
+			stream:   stream,
+		}
+	}
+}
+
+// handleSourceResult adds definitions of one dictionary, and shows the list once there is any
+func (m Dictionary) handleSourceResult(msg sourceResultMsg) (Dictionary, tea.Cmd) {
+	if msg.searchID != m.searchID {
+		// result of a cancelled search
+		return m, nil
+	}
+	res := msg.result
+	for idx, source := range m.pending {
+		if source == res.Source {
+			m.pending = append(m.pending[:idx:idx], m.pending[idx+1:]...)
+			break
+		}
+	}
+	switch {
+	case res.Err == nil, errors.Is(res.Err, dictionary.ErrorNoDef):
+		if streamer, ok := m.Dictionary.(dictionary.Streamer); ok && len(res.Definitions) != 0 {
+			m.results = append(m.results, res)
+			m.arrangeChoices(streamer)
+		}
+	case errors.Is(res.Err, context.Canceled):
+	default:
+		sourceErr := &dictionary.SourceError{Source: res.Source, Err: res.Err}
+		m.Logger.Warnln(sourceErr)
+		m.failures = append(m.failures, sourceErr.Error())
+		m.warnMsg = strings.Join(m.failures, ", ")
+	}
+	if m.state == dictionarySearching && len(m.Choices) != 0 {
+		m.cursor = 0
+		m.state = dictionarySelectDef
+	}
+	return m, waitForSource(msg.searchID, msg.stream)
+}
+
+// arrangeChoices rebuilds choices from received results in the order of the streamer,
+// cursor and selection stay on the same definitions or the ones they are folded into
+func (m *Dictionary) arrangeChoices(streamer dictionary.Streamer) {
+	selected := make(map[string]entity.Definition, len(m.Selected))
+	for idx := range m.Selected {
+		selected[definitionKey(m.Choices[idx])] = m.Choices[idx]
+	}
+	var cursorKey string
+	if m.cursor < len(m.Choices) {
+		cursorKey = definitionKey(m.Choices[m.cursor])
+	}
+	m.Choices = streamer.Arrange(m.results)
+	m.Selected = make(map[int]struct{}, len(selected))
+	m.cursor = 0
+	for idx, choice := range m.Choices {
+		key := definitionKey(choice)
+		if _, ok := selected[key]; ok {
+			m.Selected[idx] = struct{}{}
+			delete(selected, key)
+		}
+		if key == cursorKey {
+			m.cursor = idx
+		}
+	}
+	// a selected definition is gone if one of a source arriving later and shown before absorbs it
+	for _, d := range selected {
+		if idx := foldedInto(m.Choices, d); idx >= 0 {
+			m.Selected[idx] = struct{}{}
+		}
+	}
+}
+
+// foldedInto finds the choice most like d among those merged from the source of d
+func foldedInto(choices []entity.Definition, d entity.Definition) int {
+	found, best := -1, 0.0
+	for idx, choice := range choices {
+		if !slices.Contains(choice.MergedSources, d.Source) {
+			continue
+		}
+		if similarity := dictionary.Similarity(choice.Text, d.Text); found < 0 || similarity > best {
+			found, best = idx, similarity
+		}
+	}
+	return found
+}
+
+func definitionKey(d entity.Definition) string {
+	return fmt.Sprintf("%s\x00%t\x00%d\x00%s", d.Source, d.Offline, d.Sense, d.Text)
+}
+
+// handleStreamDone goes back to search if no dictionary gives any definition
+func (m Dictionary) handleStreamDone(msg streamDoneMsg) (Dictionary, tea.Cmd) {
+	if msg.searchID != m.searchID {
+		return m, nil
+	}
+	m.cancelSearch()
+	m.pending = nil
+	if m.state != dictionarySearching {
+		return m, nil
+	}
+	if len(m.failures) == 0 {
+		m.warnMsg = fmt.Sprintf("%s for %s", dictionary.ErrorNoDef.Error(), m.searchWord)
+		return m.suggestAlternatives()
+	}
+	m.SearchWord.Reset()
+	m.SearchWord.Focus()
+	m.state = dictionarySearchStart
+	return m, textinput.Blink
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+)
+
+// suggestionLimit is the max number of words in "did you mean" list
+const suggestionLimit = 8
+
+// suggestAlternatives shows known words close to the word without definition,
+// or goes back to search if there is none
+func (m Dictionary) suggestAlternatives() (Dictionary, tea.Cmd) {
+	m.suggestions = nil
+	if m.Lexicon != nil {
+		m.suggestions = m.Lexicon.Suggest(m.inputWord, suggestionLimit)
+	}
+	if len(m.suggestions) == 0 {
+		m.SearchWord.Reset()
+		m.SearchWord.Focus()
+		m.state = dictionarySearchStart
+		return m, textinput.Blink
+	}
+	m.suggestCursor = 0
+	m.state = dictionarySuggest
+	return m, nil
+}
+
+func (m Dictionary) updateSuggest(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch language.ShortcutKey(msg.String()) {
+	case "up", "w", "W":
+		m.suggestCursor = (m.suggestCursor - 1 + len(m.suggestions)) % len(m.suggestions)
+	case "down", "s", "S":
+		m.suggestCursor = (m.suggestCursor + 1) % len(m.suggestions)
+	case "enter", " ":
+		word := m.suggestions[m.suggestCursor]
+		m.suggestions = nil
+		m.SearchWord.SetValue(word)
+		return m.search(word, false)
+	case "q", "Q", "esc":
+		// back to search state
+		return m.backToSearch(), textinput.Blink
+	case "ctrl+c", "ctrl+C":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Dictionary) suggestView() string {
+	s := fmt.Sprintf("Target: %s\n", m.Target)
+	if len(m.warnMsg) != 0 {
+		s += fmt.Sprintf("\033[31m%s\033[0m\n", m.warnMsg)
+	}
+	s += fmt.Sprintf("Did you mean (instead of \033[92m%s\033[0m):\n\n", m.inputWord)
+	for idx, suggestion := range m.suggestions {
+		cursor := " "
+		if idx == m.suggestCursor {
+			cursor = ">"
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, suggestion)
+	}
+	s += "\nPress up/down to choose, enter to search\nPress q or Esc to type another word, Ctrl + c to quit."
+	return s
+}
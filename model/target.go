@@ -0,0 +1,398 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+	"github.com/s8508235/tui-dictionary/pkg/output"
+)
+
+// openTarget shows entries saved in target to edit
+func (m Dictionary) openTarget() (Dictionary, tea.Cmd) {
+	entries, err := m.Out.Entries()
+	if err != nil {
+		m.Logger.Errorln("Fail to read target:", err)
+		m.warnMsg = fmt.Sprintf("fail to read target: %s", err)
+		return m, nil
+	}
+	m.targetEntries = entries
+	m.targetFilter = textinput.New()
+	m.targetFilter.Placeholder = "filter by word or definition"
+	m.targetFilter.Focus()
+	m.targetCursor = 0
+	m.targetOpen = false
+	m.targetEditing = false
+	m.targetDeleting = false
+	m.warnMsg = ""
+	m = m.filterTarget()
+	m.SearchWord.Blur()
+	m.state = dictionaryTarget
+	return m, textinput.Blink
+}
+
+// filterTarget keeps entries whose word or definitions contain the filter, cursor stays on the same entry if it is kept
+func (m Dictionary) filterTarget() Dictionary {
+	current := -1
+	if m.targetCursor < len(m.targetVisible) {
+		current = m.targetVisible[m.targetCursor]
+	}
+	query := strings.ToLower(strings.TrimSpace(m.targetFilter.Value()))
+	m.targetVisible = make([]int, 0, len(m.targetEntries))
+	m.targetCursor = 0
+	for idx, entry := range m.targetEntries {
+		if len(query) != 0 && !strings.Contains(strings.ToLower(targetLine(entry)), query) {
+			continue
+		}
+		if idx == current {
+			m.targetCursor = len(m.targetVisible)
+		}
+		m.targetVisible = append(m.targetVisible, idx)
+	}
+	return m
+}
+
+// targetLine is an entry in one line like Quizlet target
+func targetLine(entry output.Entry) string {
+	definitions := make([]string, 0, len(entry.Definitions))
+	for _, d := range entry.Definitions {
+		definitions = append(definitions, output.FormatDefinition(d))
+	}
+	return entry.Word + "  " + strings.Join(definitions, "; ")
+}
+
+// replaceEntry puts entry at the first saved one of the same word and drops the others
+func replaceEntry(entries []output.Entry, entry output.Entry) []output.Entry {
+	result := make([]output.Entry, 0, len(entries)+1)
+	replaced := false
+	for _, saved := range entries {
+		if !output.SameWord(saved.Word, entry.Word) {
+			result = append(result, saved)
+		} else if !replaced {
+			result = append(result, entry)
+			replaced = true
+		}
+	}
+	if !replaced {
+		result = append(result, entry)
+	}
+	return result
+}
+
+// targetFocus moves cursor to the entry of word
+func (m Dictionary) targetFocus(word string) Dictionary {
+	for idx, entryIdx := range m.targetVisible {
+		if output.SameWord(m.targetEntries[entryIdx].Word, word) {
+			m.targetCursor = idx
+			break
+		}
+	}
+	return m
+}
+
+// targetEntry is the entry under cursor, nil if nothing matches filter
+func (m Dictionary) targetEntry() *output.Entry {
+	if m.targetCursor >= len(m.targetVisible) {
+		return nil
+	}
+	return &m.targetEntries[m.targetVisible[m.targetCursor]]
+}
+
+// rewriteTarget writes edited entries back to target at once
+func (m Dictionary) rewriteTarget() Dictionary {
+	if err := m.Out.Rewrite(m.targetEntries); err != nil {
+		m.Logger.Errorln("Fail to rewrite target:", err)
+		m.warnMsg = fmt.Sprintf("fail to rewrite target: %s", err)
+		// show what target really has
+		if entries, err := m.Out.Entries(); err == nil {
+			m.targetEntries = entries
+		}
+		return m.filterTarget()
+	}
+	if m.Saved != nil {
+		m.Saved = output.NewIndex(m.targetEntries)
+	}
+	m.warnMsg = ""
+	return m
+}
+
+// moveTargetEntry swaps the entry under cursor with the one shown above or below it
+func (m Dictionary) moveTargetEntry(step int) Dictionary {
+	next := m.targetCursor + step
+	if next < 0 || next >= len(m.targetVisible) {
+		return m
+	}
+	a, b := m.targetVisible[m.targetCursor], m.targetVisible[next]
+	m.targetEntries[a], m.targetEntries[b] = m.targetEntries[b], m.targetEntries[a]
+	m.targetCursor = next
+	return m.rewriteTarget()
+}
+
+// deleteTargetEntry removes the entry under cursor after it is asked twice
+func (m Dictionary) deleteTargetEntry() Dictionary {
+	entry := m.targetEntry()
+	if entry == nil {
+		return m
+	}
+	if !m.targetDeleting {
+		m.targetDeleting = true
+		m.warnMsg = fmt.Sprintf("press Ctrl + d again to delete %s", entry.Word)
+		return m
+	}
+	m.targetDeleting = false
+	idx := m.targetVisible[m.targetCursor]
+	m.targetEntries = append(m.targetEntries[:idx:idx], m.targetEntries[idx+1:]...)
+	m = m.rewriteTarget()
+	m = m.filterTarget()
+	if m.targetCursor >= len(m.targetVisible) && m.targetCursor > 0 {
+		m.targetCursor = len(m.targetVisible) - 1
+	}
+	return m
+}
+
+// researchTarget searches the word under cursor again, the definitions flushed replace saved ones
+func (m Dictionary) researchTarget() (tea.Model, tea.Cmd) {
+	entry := m.targetEntry()
+	if entry == nil {
+		return m, nil
+	}
+	m.replacing = entry.Word
+	m.bypassCache = false
+	m.SearchWord.SetValue(entry.Word)
+	return m.search(entry.Word, true)
+}
+
+func (m Dictionary) updateTarget(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.targetEditing {
+		return m.updateTargetEdit(msg)
+	}
+	if m.targetOpen {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return m.updateTargetDefinitions(msg)
+		}
+		return m, nil
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if msg.Type != tea.KeyCtrlD {
+			m.targetDeleting = false
+		}
+		switch msg.Type {
+		case tea.KeyUp, tea.KeyDown:
+			if len(m.targetVisible) != 0 {
+				step := 1
+				if msg.Type == tea.KeyUp {
+					step = len(m.targetVisible) - 1
+				}
+				m.targetCursor = (m.targetCursor + step) % len(m.targetVisible)
+			}
+			return m, nil
+		case tea.KeyShiftUp:
+			return m.moveTargetEntry(-1), nil
+		case tea.KeyShiftDown:
+			return m.moveTargetEntry(1), nil
+		case tea.KeyEnter:
+			if m.targetEntry() != nil {
+				m.targetOpen = true
+				m.targetDefCursor = 0
+				m.targetFilter.Blur()
+			}
+			return m, nil
+		case tea.KeyCtrlD:
+			return m.deleteTargetEntry(), nil
+		case tea.KeyCtrlR:
+			return m.researchTarget()
+		case tea.KeyEsc:
+			// back to search state
+			m.warnMsg = ""
+			m.state = dictionarySearchStart
+			m.SearchWord.Focus()
+			return m, textinput.Blink
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		}
+	}
+	var cmd tea.Cmd
+	before := m.targetFilter.Value()
+	m.targetFilter, cmd = m.targetFilter.Update(msg)
+	if m.targetFilter.Value() != before {
+		m = m.filterTarget()
+	}
+	return m, cmd
+}
+
+// updateTargetDefinitions handles keys on definitions of the entry under cursor
+func (m Dictionary) updateTargetDefinitions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	entry := m.targetEntry()
+	if entry == nil {
+		m.targetOpen = false
+		return m, nil
+	}
+	definitions := entry.Definitions
+	if msg.Type != tea.KeyCtrlD {
+		m.targetDeleting = false
+	}
+	switch language.ShortcutKey(msg.String()) {
+	case "up", "w", "W":
+		if len(definitions) != 0 {
+			m.targetDefCursor = (m.targetDefCursor - 1 + len(definitions)) % len(definitions)
+		}
+	case "down", "s", "S":
+		if len(definitions) != 0 {
+			m.targetDefCursor = (m.targetDefCursor + 1) % len(definitions)
+		}
+	case "shift+up", "shift+down":
+		next := m.targetDefCursor - 1
+		if msg.Type == tea.KeyShiftDown {
+			next = m.targetDefCursor + 1
+		}
+		if next < 0 || next >= len(definitions) {
+			return m, nil
+		}
+		definitions[m.targetDefCursor], definitions[next] = definitions[next], definitions[m.targetDefCursor]
+		m.targetDefCursor = next
+		return m.rewriteTarget(), nil
+	case "enter", "e", "E":
+		if len(definitions) == 0 {
+			return m, nil
+		}
+		m.targetEdit = textinput.New()
+		m.targetEdit.SetValue(definitions[m.targetDefCursor].Text)
+		m.targetEdit.CursorEnd()
+		m.targetEdit.Focus()
+		m.targetEditing = true
+		return m, textinput.Blink
+	case "ctrl+d":
+		if len(definitions) == 0 {
+			return m, nil
+		}
+		if len(definitions) == 1 {
+			// a word without definition is deleted as a whole
+			m.targetOpen = false
+			m.targetFilter.Focus()
+			return m.deleteTargetEntry(), nil
+		}
+		if !m.targetDeleting {
+			m.targetDeleting = true
+			m.warnMsg = "press Ctrl + d again to delete the definition"
+			return m, nil
+		}
+		m.targetDeleting = false
+		entry.Definitions = append(definitions[:m.targetDefCursor:m.targetDefCursor], definitions[m.targetDefCursor+1:]...)
+		if m.targetDefCursor >= len(entry.Definitions) {
+			m.targetDefCursor = len(entry.Definitions) - 1
+		}
+		return m.rewriteTarget(), nil
+	case "ctrl+r":
+		return m.researchTarget()
+	case "q", "Q", "esc":
+		// back to entries
+		m.warnMsg = ""
+		m.targetOpen = false
+		m.targetFilter.Focus()
+		return m, textinput.Blink
+	case "ctrl+c", "ctrl+C":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// updateTargetEdit edits text of the definition under cursor inline
+func (m Dictionary) updateTargetEdit(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyEnter:
+			m.targetEditing = false
+			text := strings.TrimSpace(m.targetEdit.Value())
+			entry := m.targetEntry()
+			if len(text) == 0 || entry == nil {
+				m.warnMsg = "definition is not changed, delete it with Ctrl + d instead"
+				return m, nil
+			}
+			// only text is edited, part of speech and labels are kept apart by targets like JSON Lines and SQLite,
+			// others read them back as part of text
+			d := &entry.Definitions[m.targetDefCursor]
+			if text != d.Text {
+				d.Text = text
+				return m.rewriteTarget(), nil
+			}
+			return m, nil
+		case tea.KeyEsc:
+			m.targetEditing = false
+			return m, nil
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		}
+	}
+	var cmd tea.Cmd
+	m.targetEdit, cmd = m.targetEdit.Update(msg)
+	return m, cmd
+}
+
+func (m Dictionary) targetView() string {
+	header := fmt.Sprintf("Target: %s (%d words)\nFilter: %s\n\n", m.Target, len(m.targetEntries), m.targetFilter.View())
+	if len(m.warnMsg) != 0 {
+		header += fmt.Sprintf("\033[31m%s\033[0m\n\n", m.warnMsg)
+	}
+	if entry := m.targetEntry(); m.targetOpen && entry != nil {
+		return header + m.targetDefinitionsView(*entry)
+	}
+	footer := "\nPress up/down to choose, enter to edit definitions, shift + up/down to move the word\n"
+	footer += "Ctrl + d to delete the word, Ctrl + r to search it again and replace its definitions\n"
+	footer += "Type to filter, Esc to go back, Ctrl + c to quit."
+	if len(m.targetVisible) == 0 {
+		return header + "no saved word\n" + footer
+	}
+	pageLineCount := m.height - strings.Count(header, "\n") - strings.Count(footer, "\n") - 1
+	if pageLineCount < 1 {
+		pageLineCount = len(m.targetVisible)
+	}
+	currentPage := m.targetCursor / pageLineCount
+	start := currentPage * pageLineCount
+	var content string
+	for idx := start; idx < len(m.targetVisible) && idx < start+pageLineCount; idx++ {
+		entry := m.targetEntries[m.targetVisible[idx]]
+		cursor := " "
+		if idx == m.targetCursor {
+			cursor = ">"
+		}
+		prefix := fmt.Sprintf("%s %3d ", cursor, m.targetVisible[idx]+1)
+		word, rest := entry.Word, strings.TrimPrefix(targetLine(entry), entry.Word+"  ")
+		// text is cut by display width before coloring so wide letters and escape codes are counted right
+		if width := m.width - len(prefix); m.width > 4 && runewidth.StringWidth(word+"  "+rest) > width {
+			if runewidth.StringWidth(word)+len("  ...") > width {
+				word, rest = runewidth.Truncate(word, width-len("  "), "..."), ""
+			} else {
+				rest = runewidth.Truncate(rest, width-runewidth.StringWidth(word)-len("  "), "...")
+			}
+		}
+		content += fmt.Sprintf("%s\033[92m%s\033[0m  %s\n", prefix, word, rest)
+	}
+	pages := (len(m.targetVisible) + pageLineCount - 1) / pageLineCount
+	footer = fmt.Sprintf("\033[38:2:255:165:0mpage: %2d / %2d\033[0m", currentPage+1, pages) + footer
+	return header + content + footer
+}
+
+func (m Dictionary) targetDefinitionsView(entry output.Entry) string {
+	s := fmt.Sprintf("Definitions of \033[92m%s\033[0m:\n", entry.Word)
+	for idx, d := range entry.Definitions {
+		cursor := " "
+		if idx == m.targetDefCursor {
+			cursor = ">"
+		}
+		if idx == m.targetDefCursor && m.targetEditing {
+			s += fmt.Sprintf("%s %2d %s\n", cursor, idx+1, m.targetEdit.View())
+			continue
+		}
+		s += fmt.Sprintf("%s %2d %s\n", cursor, idx+1, output.FormatDefinition(d))
+	}
+	if m.targetEditing {
+		return s + "\nPress enter to save the definition, Esc to cancel"
+	}
+	s += "\nPress up/down to choose, enter or e to edit, shift + up/down to move the definition\n"
+	s += "Ctrl + d to delete the definition, Ctrl + r to search the word again\n"
+	s += "q or Esc to go back to words, Ctrl + c to quit."
+	return s
+}
@@ -0,0 +1,205 @@
+// Package anki exports saved words as Anki deck package (.apkg), a zipped SQLite collection with media manifest
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1" //nolint:gosec // checksum format of Anki, not for security
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/output"
+	"github.com/s8508235/tui-dictionary/pkg/tools"
+	_ "modernc.org/sqlite" // register sqlite driver
+)
+
+// NoteType decides cards made of a note
+type NoteType string
+
+const (
+	// Basic makes a card asking front
+	Basic NoteType = "basic"
+	// Reversed makes another card asking back
+	Reversed NoteType = "reversed"
+)
+
+var ErrUnknownNoteType = errors.New("unknown note type")
+
+// ParseNoteType accepts basic or reversed
+func ParseNoteType(s string) (NoteType, error) {
+	switch NoteType(strings.ToLower(s)) {
+	case Basic:
+		return Basic, nil
+	case Reversed:
+		return Reversed, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownNoteType, s)
+	}
+}
+
+// Package collects notes of one deck
+type Package struct {
+	Deck     string
+	NoteType NoteType
+	// ModelName is the note type name shown in Anki, notes update only if it is the same as last export
+	ModelName string
+	// Tags are added to every note besides language and source dictionary tags
+	Tags    []string
+	entries []output.Entry
+	index   map[string]int // guid to position in entries
+}
+
+func New(deck string, noteType NoteType) *Package {
+	modelName := "tui-dictionary Basic"
+	if noteType == Reversed {
+		modelName = "tui-dictionary Basic (and reversed card)"
+	}
+	return &Package{
+		Deck:      deck,
+		NoteType:  noteType,
+		ModelName: modelName,
+		index:     make(map[string]int),
+	}
+}
+
+// GUID is the same for the same word and language so that re-imported notes update instead of duplicating
+func GUID(entry output.Entry) string {
+	sum := sha1.Sum([]byte(output.WordKey(entry.Word) + "\x1f" + string(entry.Language))) //nolint:gosec
+	return hex.EncodeToString(sum[:8])
+}
+
+// Add keeps entries as notes, a later entry of the same word and language replaces the earlier one
+func (p *Package) Add(entries ...output.Entry) {
+	for _, entry := range entries {
+		guid := GUID(entry)
+		if idx, ok := p.index[guid]; ok {
+			p.entries[idx] = entry
+			continue
+		}
+		p.index[guid] = len(p.entries)
+		p.entries = append(p.entries, entry)
+	}
+}
+
+// Len is the number of notes
+func (p *Package) Len() int {
+	return len(p.entries)
+}
+
+// stableID gives a positive id from name, so the deck and note type are found again by later exports
+func stableID(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64() >> 12) //nolint:gosec // 52 bits always fits
+}
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// checksum is the first 8 hex digits of SHA-1 of field without HTML, Anki finds duplicates by it
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(htmlTagRe.ReplaceAllString(field, ""))) //nolint:gosec
+	n, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+	return n
+}
+
+// WriteFile writes the package to path, an existing file is replaced at once
+func (p *Package) WriteFile(path string) error {
+	dir, err := os.MkdirTemp("", "tui-dictionary-apkg")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	collection := filepath.Join(dir, "collection.anki2")
+	if err := p.writeCollection(collection); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(collection)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"collection.anki2", data},
+		// no media file, the manifest maps numbered file names in the package to media names
+		{"media", []byte("{}")},
+	} {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return tools.WriteFileAtomic(path, buf.Bytes(), 0600)
+}
+
+func (p *Package) writeCollection(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	now := time.Now()
+	deckID := stableID("deck\x1f" + p.Deck)
+	m := newModel(stableID("model\x1f"+p.ModelName), p.ModelName, p.NoteType, deckID, now.Unix())
+	conf, models, decks, err := collectionJSON(m, newDeck(deckID, p.Deck, now.Unix()))
+	if err != nil {
+		return err
+	}
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if _, err := db.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		dayStart.Unix(), now.UnixMilli(), now.UnixMilli(), conf, models, decks, defaultDconf); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// ids are creation time in milliseconds in Anki, one more for each note and card to keep them unique
+	nextID := now.UnixMilli()
+	for idx, entry := range p.entries {
+		front, back, tags := output.AnkiNote(entry)
+		tags = append(tags, p.Tags...)
+		noteID := nextID
+		nextID++
+		if _, err := tx.Exec(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, GUID(entry), m.ID, now.Unix(), " "+strings.Join(tags, " ")+" ",
+			front+"\x1f"+back, htmlTagRe.ReplaceAllString(front, ""), checksum(front)); err != nil {
+			return err
+		}
+		for ord := range m.Tmpls {
+			if _, err := tx.Exec(`INSERT INTO cards VALUES (?, ?, ?, ?, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+				nextID, noteID, deckID, ord, now.Unix(), idx+1); err != nil {
+				return err
+			}
+			nextID++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return db.Close()
+}
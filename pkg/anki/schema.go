@@ -0,0 +1,169 @@
+package anki
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// schema is collection schema version 11, the one every Anki release still imports
+const schema = `
+CREATE TABLE col (
+	id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+	ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+	conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+);
+CREATE TABLE notes (
+	id integer primary key, guid text not null, mid integer not null, mod integer not null,
+	usn integer not null, tags text not null, flds text not null, sfld integer not null,
+	csum integer not null, flags integer not null, data text not null
+);
+CREATE TABLE cards (
+	id integer primary key, nid integer not null, did integer not null, ord integer not null,
+	mod integer not null, usn integer not null, type integer not null, queue integer not null,
+	due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+	lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+	flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+	id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+	ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+	type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn on notes (usn);
+CREATE INDEX ix_cards_usn on cards (usn);
+CREATE INDEX ix_revlog_usn on revlog (usn);
+CREATE INDEX ix_cards_nid on cards (nid);
+CREATE INDEX ix_cards_sched on cards (did, queue, due);
+CREATE INDEX ix_revlog_cid on revlog (cid);
+CREATE INDEX ix_notes_csum on notes (csum);
+`
+
+const defaultCSS = `.card {
+	font-family: arial;
+	font-size: 20px;
+	text-align: center;
+	color: black;
+	background-color: white;
+}
+ol {
+	text-align: left;
+}
+`
+
+type field struct {
+	Name   string        `json:"name"`
+	Ord    int           `json:"ord"`
+	Sticky bool          `json:"sticky"`
+	RTL    bool          `json:"rtl"`
+	Font   string        `json:"font"`
+	Size   int           `json:"size"`
+	Media  []interface{} `json:"media"`
+}
+
+type template struct {
+	Name  string      `json:"name"`
+	Ord   int         `json:"ord"`
+	QFmt  string      `json:"qfmt"`
+	AFmt  string      `json:"afmt"`
+	BQFmt string      `json:"bqfmt"`
+	BAFmt string      `json:"bafmt"`
+	Did   interface{} `json:"did"`
+}
+
+type model struct {
+	ID        int64           `json:"id"`
+	Name      string          `json:"name"`
+	Type      int             `json:"type"`
+	Mod       int64           `json:"mod"`
+	Usn       int             `json:"usn"`
+	Sortf     int             `json:"sortf"`
+	Did       int64           `json:"did"`
+	Tmpls     []template      `json:"tmpls"`
+	Flds      []field         `json:"flds"`
+	CSS       string          `json:"css"`
+	LatexPre  string          `json:"latexPre"`
+	LatexPost string          `json:"latexPost"`
+	Req       [][]interface{} `json:"req"`
+	Tags      []string        `json:"tags"`
+	Vers      []interface{}   `json:"vers"`
+}
+
+type deck struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Desc      string `json:"desc"`
+	Mod       int64  `json:"mod"`
+	Usn       int    `json:"usn"`
+	Collapsed bool   `json:"collapsed"`
+	Dyn       int    `json:"dyn"`
+	Conf      int    `json:"conf"`
+	ExtendNew int    `json:"extendNew"`
+	ExtendRev int    `json:"extendRev"`
+	NewToday  [2]int `json:"newToday"`
+	RevToday  [2]int `json:"revToday"`
+	LrnToday  [2]int `json:"lrnToday"`
+	TimeToday [2]int `json:"timeToday"`
+}
+
+const defaultDconf = `{"1": {"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true,
+"new": {"bury": true, "delays": [1, 10], "initialFactor": 2500, "ints": [1, 4, 7], "order": 1, "perDay": 20, "separate": true},
+"rev": {"bury": true, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "minSpace": 1, "perDay": 100},
+"lapse": {"delays": [10], "leechAction": 0, "leechFails": 8, "minInt": 1, "mult": 0}}}`
+
+// newModel gives note type of front and back fields, reversed one has a card for each direction
+func newModel(id int64, name string, noteType NoteType, did, mod int64) model {
+	m := model{
+		ID:        id,
+		Name:      name,
+		Mod:       mod,
+		Usn:       -1,
+		Did:       did,
+		CSS:       defaultCSS,
+		LatexPre:  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		LatexPost: "\\end{document}",
+		Tags:      []string{},
+		Vers:      []interface{}{},
+		Flds: []field{
+			{Name: "Front", Ord: 0, Font: "Arial", Size: 20, Media: []interface{}{}},
+			{Name: "Back", Ord: 1, Font: "Arial", Size: 20, Media: []interface{}{}},
+		},
+		Tmpls: []template{
+			{Name: "Card 1", Ord: 0, QFmt: "{{Front}}", AFmt: "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}"},
+		},
+		Req: [][]interface{}{{0, "any", []int{0}}},
+	}
+	if noteType == Reversed {
+		m.Tmpls = append(m.Tmpls, template{Name: "Card 2", Ord: 1, QFmt: "{{Back}}", AFmt: "{{FrontSide}}\n\n<hr id=answer>\n\n{{Front}}"})
+		m.Req = append(m.Req, []interface{}{1, "any", []int{1}})
+	}
+	return m
+}
+
+func newDeck(id int64, name string, mod int64) deck {
+	return deck{ID: id, Name: name, Mod: mod, Conf: 1, ExtendNew: 10, ExtendRev: 50}
+}
+
+// collectionJSON gives conf, models, decks and dconf columns of col table
+func collectionJSON(m model, d deck) (conf, models, decks string, err error) {
+	confData, err := json.Marshal(map[string]interface{}{
+		"activeDecks": []int64{d.ID}, "curDeck": d.ID, "curModel": strconv.FormatInt(m.ID, 10),
+		"addToCur": true, "collapseTime": 1200, "dueCounts": true, "estTimes": true, "newBury": true,
+		"newSpread": 0, "nextPos": 1, "sortBackwards": false, "sortType": "noteFld", "timeLim": 0,
+	})
+	if err != nil {
+		return "", "", "", err
+	}
+	modelsData, err := json.Marshal(map[string]model{strconv.FormatInt(m.ID, 10): m})
+	if err != nil {
+		return "", "", "", err
+	}
+	decksData, err := json.Marshal(map[string]deck{
+		"1":                         newDeck(1, "Default", d.Mod),
+		strconv.FormatInt(d.ID, 10): d,
+	})
+	if err != nil {
+		return "", "", "", err
+	}
+	return string(confData), string(modelsData), string(decksData), nil
+}
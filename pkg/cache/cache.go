@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/tools"
+)
+
+// Entry is the definitions of a word from one dictionary
+type Entry struct {
+	Word        string              `json:"word"`
+	Source      string              `json:"source"`
+	Definitions []entity.Definition `json:"definitions"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// DefaultSaveDelay is how long Put waits for more lookups before writing cache back to disk
+const DefaultSaveDelay = 2 * time.Second
+
+// Cache keeps lookups on disk as a single json file
+type Cache struct {
+	path string
+	// TTL is how long an entry stays valid, zero means forever
+	TTL time.Duration
+	// MaxSize is the max number of entries, zero means no limit
+	MaxSize int
+	// SaveDelay batches lookups put within it into one write of the file
+	SaveDelay time.Duration
+	mu        sync.Mutex
+	entries   map[string]Entry
+	// saving is the write waiting for SaveDelay, dirty means entries are changed since the last write
+	saving *time.Timer
+	dirty  bool
+	// err is the last failed write in background, it is given by the next Put or Close
+	err error
+}
+
+// DefaultPath gives lookup cache path under user cache directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tui-dictionary", "lookup.json"), nil
+}
+
+// New loads cache from path, a missing file is an empty cache
+func New(path string, ttl time.Duration, maxSize int) (*Cache, error) {
+	c := &Cache{
+		path:      path,
+		TTL:       ttl,
+		MaxSize:   maxSize,
+		SaveDelay: DefaultSaveDelay,
+		entries:   make(map[string]Entry),
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	entries, err := decodeFile(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		c.entries[key(entry.Source, entry.Word)] = entry
+	}
+	return c, nil
+}
+
+// Clear removes the cache file
+func Clear(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func key(source, word string) string {
+	return source + "\x00" + strings.ToLower(word)
+}
+
+func (c *Cache) expired(entry Entry, now time.Time) bool {
+	return c.TTL > 0 && now.Sub(entry.CreatedAt) > c.TTL
+}
+
+// Get gives the definitions of word from source if not expired
+func (c *Cache) Get(source, word string) ([]entity.Definition, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key(source, word)]
+	if !ok || c.expired(entry, time.Now()) {
+		return nil, false
+	}
+	return clone(entry.Definitions), true
+}
+
+// Entries gives every stored lookup of word even if it is expired
+func (c *Cache) Entries(word string) []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]Entry, 0)
+	for _, entry := range c.entries {
+		if strings.EqualFold(entry.Word, word) {
+			entry.Definitions = clone(entry.Definitions)
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Source < entries[j].Source
+	})
+	return entries
+}
+
+// Put stores the definitions of word from source, cache is written back to disk after SaveDelay
+// with other lookups put in the meantime. The error is of the last write failed in background.
+func (c *Cache) Put(source, word string, definitions []entity.Definition) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.entries[key(source, word)] = Entry{
+		Word:        word,
+		Source:      source,
+		Definitions: clone(definitions),
+		CreatedAt:   now,
+	}
+	c.evict(now)
+	c.dirty = true
+	if c.saving == nil {
+		c.saving = time.AfterFunc(c.SaveDelay, c.flush)
+	}
+	err := c.err
+	c.err = nil
+	return err
+}
+
+// flush writes the lookups put during SaveDelay
+func (c *Cache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saving = nil
+	if c.dirty {
+		c.dirty = false
+		c.err = c.save()
+	}
+}
+
+// Close writes lookups not saved yet, cache is still usable after it
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.saving != nil {
+		c.saving.Stop()
+		c.saving = nil
+	}
+	err := c.err
+	c.err = nil
+	if c.dirty {
+		c.dirty = false
+		if saveErr := c.save(); saveErr != nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// clone copies definitions so callers can change them while cache is written to disk
+func clone(definitions []entity.Definition) []entity.Definition {
+	cloned := slices.Clone(definitions)
+	for idx := range cloned {
+		cloned[idx].Examples = slices.Clone(cloned[idx].Examples)
+		cloned[idx].Labels = slices.Clone(cloned[idx].Labels)
+		cloned[idx].MergedSources = slices.Clone(cloned[idx].MergedSources)
+	}
+	return cloned
+}
+
+// evict drops expired entries and then the oldest ones over MaxSize
+func (c *Cache) evict(now time.Time) {
+	for k, entry := range c.entries {
+		if c.expired(entry, now) {
+			delete(c.entries, k)
+		}
+	}
+	if c.MaxSize <= 0 || len(c.entries) <= c.MaxSize {
+		return
+	}
+	keys := make([]string, 0, len(c.entries))
+	for k := range c.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].CreatedAt.Before(c.entries[keys[j]].CreatedAt)
+	})
+	for _, k := range keys[:len(keys)-c.MaxSize] {
+		delete(c.entries, k)
+	}
+}
+
+// save writes cache back to disk atomically
+func (c *Cache) save() error {
+	entries := make([]Entry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	data, err := encodeFile(entries)
+	if err != nil {
+		return err
+	}
+	return tools.WriteFileAtomic(c.path, data, 0600)
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
+
+// fileVersion is the layout of cache file written now,
+// files before versioning are a bare list of entries with definitions keyed by Go field names
+const fileVersion = 1
+
+var ErrNewerVersion = errors.New("cache file is written by a newer version")
+
+type file struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// legacyDefinition is entity.Definition before it has json tags
+type legacyDefinition struct {
+	Text          string
+	PartOfSpeech  string
+	Source        string
+	Examples      []string
+	Sense         int
+	Labels        []string
+	MergedSources []string
+	Offline       bool
+}
+
+type legacyEntry struct {
+	Word        string             `json:"word"`
+	Source      string             `json:"source"`
+	Definitions []legacyDefinition `json:"definitions"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// decodeFile reads entries of cache file in any known layout
+func decodeFile(data []byte) ([]Entry, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		legacy := make([]legacyEntry, 0)
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, err
+		}
+		entries := make([]Entry, 0, len(legacy))
+		for _, l := range legacy {
+			entry := Entry{Word: l.Word, Source: l.Source, CreatedAt: l.CreatedAt, Definitions: make([]entity.Definition, 0, len(l.Definitions))}
+			for _, d := range l.Definitions {
+				entry.Definitions = append(entry.Definitions, entity.Definition(d))
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version > fileVersion {
+		return nil, fmt.Errorf("%w: %d", ErrNewerVersion, f.Version)
+	}
+	return f.Entries, nil
+}
+
+func encodeFile(entries []Entry) ([]byte, error) {
+	return json.Marshal(file{Version: fileVersion, Entries: entries})
+}
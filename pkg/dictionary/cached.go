@@ -0,0 +1,58 @@
+package dictionary
+
+import (
+	"context"
+
+	"github.com/s8508235/tui-dictionary/pkg/cache"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	log "github.com/sirupsen/logrus"
+)
+
+type bypassCacheKey struct{}
+
+// WithoutCache makes the search skip the cached definitions and refresh them
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func shouldBypassCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+// CachedDictionary answers from cache before asking the wrapped dictionary
+type CachedDictionary struct {
+	Dictionary Interface
+	Cache      *cache.Cache
+	Logger     *log.Logger
+}
+
+func NewCachedDictionary(logger *log.Logger, dictionary Interface, c *cache.Cache) Interface {
+	return &CachedDictionary{
+		Dictionary: dictionary,
+		Cache:      c,
+		Logger:     logger,
+	}
+}
+
+func (c *CachedDictionary) Search(ctx context.Context, word string) ([]entity.Definition, error) {
+	name := c.Dictionary.GetName()
+	if !shouldBypassCache(ctx) {
+		if definitions, ok := c.Cache.Get(name, word); ok {
+			c.Logger.Debugln("cache hit", name, word)
+			return definitions, nil
+		}
+	}
+	definitions, err := c.Dictionary.Search(ctx, word)
+	if err != nil {
+		return definitions, err
+	}
+	if err := c.Cache.Put(name, word, definitions); err != nil {
+		c.Logger.Warnln("Fail to write cache:", err)
+	}
+	return definitions, nil
+}
+
+func (c *CachedDictionary) GetName() string {
+	return c.Dictionary.GetName()
+}
@@ -2,6 +2,7 @@ package dictionary
 
 import (
 	"github.com/gocolly/colly/v2"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -13,6 +14,20 @@ const (
 type cambridgeCrawler struct {
 	Selector   string
 	SearchURL  func(string) string
-	SearchFunc func(results *[]string, counter *int) func(e *colly.HTMLElement)
+	SearchFunc func(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement)
 	Logger     *log.Logger
 }
+
+func cambridgeSearch(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement) {
+	return func(e *colly.HTMLElement) {
+		block := e.DOM.Closest("div.def-block")
+		*results = append(*results, entity.Definition{
+			Text:         e.Text,
+			PartOfSpeech: firstText(e.DOM.Closest("div.entry-body__el").Find("div.posgram span.pos")),
+			Examples:     selectionTexts(block.Find("div.examp span.eg")),
+			Labels:       selectionTexts(block.Find("span.def-info span.usage, span.def-info span.gc")),
+			Sense:        *counter + 1,
+		})
+		*counter += 1
+	}
+}
@@ -0,0 +1,114 @@
+package dictionary
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
+
+// DefaultSimilarity is the token overlap to treat two definitions as the same one
+const DefaultSimilarity = 0.8
+
+// normalizeDefinition ignores case, punctuation and extra spaces of definition
+func normalizeDefinition(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, text)
+	return strings.TrimSpace(re.ReplaceAllString(text, " "))
+}
+
+func tokenSet(normalized string) map[string]struct{} {
+	tokens := make(map[string]struct{})
+	for _, token := range strings.Fields(normalized) {
+		tokens[token] = struct{}{}
+	}
+	return tokens
+}
+
+// tokenSimilarity is the Jaccard index of two token sets
+func tokenSimilarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for token := range a {
+		if _, ok := b[token]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Similarity is the token overlap of two definitions, 1 if they match after normalization
+func Similarity(a, b string) float64 {
+	normalizedA, normalizedB := normalizeDefinition(a), normalizeDefinition(b)
+	if normalizedA == normalizedB {
+		return 1
+	}
+	return tokenSimilarity(tokenSet(normalizedA), tokenSet(normalizedB))
+}
+
+// Dedupe folds definitions of different sources matching after normalization or overlapping by at least
+// similarity of tokens into the first one of them, which records where the others came from.
+// Senses of the same source are always kept apart.
+func Dedupe(definitions []entity.Definition, similarity float64) []entity.Definition {
+	type kept struct {
+		normalized string
+		tokens     map[string]struct{}
+	}
+	deduped := make([]entity.Definition, 0, len(definitions))
+	keys := make([]kept, 0, len(definitions))
+	for _, d := range definitions {
+		normalized := normalizeDefinition(d.Text)
+		tokens := tokenSet(normalized)
+		merged := false
+		for idx, key := range keys {
+			if d.Source == deduped[idx].Source || containsString(deduped[idx].MergedSources, d.Source) {
+				continue
+			}
+			if key.normalized != normalized && tokenSimilarity(key.tokens, tokens) < similarity {
+				continue
+			}
+			deduped[idx] = mergeDefinition(deduped[idx], d)
+			merged = true
+			break
+		}
+		if !merged {
+			deduped = append(deduped, d)
+			keys = append(keys, kept{normalized: normalized, tokens: tokens})
+		}
+	}
+	return deduped
+}
+
+// mergeDefinition keeps text of kept definition and takes what it misses from the duplicate
+func mergeDefinition(kept, duplicate entity.Definition) entity.Definition {
+	sources := append([]string{duplicate.Source}, duplicate.MergedSources...)
+	for _, source := range sources {
+		if len(source) != 0 && source != kept.Source && !containsString(kept.MergedSources, source) {
+			kept.MergedSources = append(kept.MergedSources, source)
+		}
+	}
+	if len(kept.PartOfSpeech) == 0 {
+		kept.PartOfSpeech = duplicate.PartOfSpeech
+	}
+	for _, example := range duplicate.Examples {
+		if !containsString(kept.Examples, example) {
+			kept.Examples = append(kept.Examples, example)
+		}
+	}
+	return kept
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,51 +1,153 @@
 package dictionary
 
 import (
+	"context"
 	"errors"
-	"net/textproto"
-	"syscall"
+	"fmt"
 
+	"github.com/s8508235/tui-dictionary/pkg/dictproto"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/net/dict"
 )
 
+// DefaultDICTLimit is the max number of definitions taken from each database by default
+const DefaultDICTLimit = 3
+
+var ErrUnknownDatabase = errors.New("unknown database")
+
+// DICTClient searches a DICT server (RFC 2229) like dict.org, it is safe for concurrent use
 type DICTClient struct {
-	network          string
-	addr             string
-	Client           *dict.Client
-	Logger           *log.Logger
-	DictionaryPrefix string
-}
-
-func (d *DICTClient) Search(word string) ([]string, error) {
-	result := make([]string, 0, 3)
-	defs, err := d.Client.Define(d.DictionaryPrefix, word)
-	if err != nil {
-		if errors.Is(err, syscall.EPIPE) {
-			d.Logger.Infoln("=== reconnect to dict.org ===")
-			// reconnect
-			client, err := dict.Dial(d.network, d.addr)
-			if err != nil {
-				return nil, err
-			}
-			d.Client = client
-			return d.Search(word)
-		}
-		textprotoError, valid := err.(*textproto.Error)
-		if !valid || textprotoError.Code != 552 {
+	pool   *dictproto.Pool
+	Logger *log.Logger
+	// Databases are searched one at a time in order,
+	// "*" means every database of server and "!" the first one having the word
+	Databases []string
+	// Limit is the max number of definitions from each database, zero means no limit
+	Limit int
+}
+
+func (d *DICTClient) Search(ctx context.Context, word string) ([]entity.Definition, error) {
+	result := make([]entity.Definition, 0, len(d.Databases)*DefaultDICTLimit)
+	for _, database := range d.Databases {
+		definitions, err := d.SearchDatabase(ctx, database, word)
+		if errors.Is(err, ErrorNoDef) {
+			continue
+		} else if err != nil {
 			d.Logger.Error(err)
 			return result, err
 		}
-		return result, ErrorNoDef
+		result = append(result, definitions...)
 	}
-	if len(defs) == 0 {
+	if len(result) == 0 {
 		return result, ErrorNoDef
 	}
-	for idx, def := range defs {
-		if idx >= 3 {
-			break
+	return result, nil
+}
+
+// SearchDatabase defines word in a single database, Source of each definition is the database name
+func (d *DICTClient) SearchDatabase(ctx context.Context, database, word string) ([]entity.Definition, error) {
+	var defs []dictproto.Definition
+	err := d.pool.Do(ctx, func(conn *dictproto.Conn) error {
+		var err error
+		defs, err = conn.Define(database, word)
+		return err
+	})
+	switch {
+	case errors.Is(err, dictproto.ErrNoMatch):
+		return nil, ErrorNoDef
+	case errors.Is(err, dictproto.ErrInvalidDatabase):
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDatabase, database)
+	case err != nil:
+		return nil, err
+	}
+	result := make([]entity.Definition, 0, len(defs))
+	// "*" gives articles of many databases, each of them is limited on its own
+	senses := make(map[string]int)
+	for _, def := range defs {
+		if d.Limit > 0 && senses[def.Database] >= d.Limit {
+			continue
 		}
-		result = append(result, string(def.Text))
+		senses[def.Database]++
+		result = append(result, entity.Definition{
+			Text:   def.Text,
+			Source: def.Database,
+			Sense:  senses[def.Database],
+		})
+	}
+	if len(result) == 0 {
+		return nil, ErrorNoDef
 	}
 	return result, nil
 }
+
+// Match lists distinct headwords matching word by strategy in Databases
+func (d *DICTClient) Match(ctx context.Context, strategy, word string) ([]string, error) {
+	words := make([]string, 0)
+	for _, database := range d.Databases {
+		matches, err := d.MatchDatabase(ctx, database, strategy, word)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if !containsString(words, match.Word) {
+				words = append(words, match.Word)
+			}
+		}
+	}
+	return words, nil
+}
+
+// MatchDatabase runs MATCH in a single database, strategies like prefix, soundex or lev depend on server
+func (d *DICTClient) MatchDatabase(ctx context.Context, database, strategy, word string) ([]dictproto.Match, error) {
+	var matches []dictproto.Match
+	err := d.pool.Do(ctx, func(conn *dictproto.Conn) error {
+		var err error
+		matches, err = conn.Match(database, strategy, word)
+		return err
+	})
+	switch {
+	case errors.Is(err, dictproto.ErrNoMatch):
+		return []dictproto.Match{}, nil
+	case errors.Is(err, dictproto.ErrInvalidDatabase):
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDatabase, database)
+	case errors.Is(err, dictproto.ErrInvalidStrategy):
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, strategy)
+	}
+	return matches, err
+}
+
+// ShowDatabases lists databases of server
+func (d *DICTClient) ShowDatabases(ctx context.Context) ([]dictproto.Item, error) {
+	var items []dictproto.Item
+	err := d.pool.Do(ctx, func(conn *dictproto.Conn) error {
+		var err error
+		items, err = conn.ShowDatabases()
+		return err
+	})
+	return items, err
+}
+
+// ShowStrategies lists match strategies of server
+func (d *DICTClient) ShowStrategies(ctx context.Context) ([]dictproto.Item, error) {
+	var items []dictproto.Item
+	err := d.pool.Do(ctx, func(conn *dictproto.Conn) error {
+		var err error
+		items, err = conn.ShowStrategies()
+		return err
+	})
+	return items, err
+}
+
+// Pool gives the connections to server for tuning reconnection
+func (d *DICTClient) Pool() *dictproto.Pool {
+	return d.pool
+}
+
+func (d *DICTClient) GetName() string {
+	return d.pool.Addr()
+}
+
+// Close quits the server
+func (d *DICTClient) Close() error {
+	return d.pool.Close()
+}
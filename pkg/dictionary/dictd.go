@@ -0,0 +1,240 @@
+package dictionary
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictzip"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	log "github.com/sirupsen/logrus"
+)
+
+// dictdBase64 is the alphabet of offsets and lengths in .index, most significant digit first
+const dictdBase64 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// dictdSenseRe matches the start of a numbered sense like "v 1: ..." in WordNet or "2. ..." in GCIDE
+var dictdSenseRe = regexp.MustCompile(`^\s*(?:(n|v|adj|adv)\s+)?(\d+)[:.]\s+`)
+
+type dictdEntry struct {
+	headword string
+	offset   int64
+	size     int64
+}
+
+// Dictd reads a dictd database (.index and .dict.dz) without a DICT server
+type Dictd struct {
+	Name   string
+	Logger *log.Logger
+	// entries are keyed by headword folded with foldDictdHeadword
+	entries map[string][]dictdEntry
+	// headwords are the sorted keys of entries
+	headwords []string
+	title     string
+	dict      *dictzip.Reader
+}
+
+// NewDictdDictionary loads the whole index of indexPath into memory, .dict(.dz) is looked up next to it
+func NewDictdDictionary(logger *log.Logger, indexPath string) (*Dictd, error) {
+	base := strings.TrimSuffix(indexPath, filepath.Ext(indexPath))
+	var dict *dictzip.Reader
+	for _, path := range []string{base + ".dict.dz", base + ".dict"} {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		var err error
+		if dict, err = dictzip.Open(path); err != nil {
+			return nil, err
+		}
+		break
+	}
+	if dict == nil {
+		return nil, fmt.Errorf("no .dict or .dict.dz for %s", indexPath)
+	}
+	d := &Dictd{
+		Name:    filepath.Base(base),
+		Logger:  logger,
+		entries: make(map[string][]dictdEntry),
+		dict:    dict,
+	}
+	if err := d.readIndex(indexPath); err != nil {
+		_ = dict.Close()
+		return nil, err
+	}
+	d.title = d.Name
+	if short, ok := d.entries[foldDictdHeadword("00-database-short")]; ok {
+		if article, err := d.article(short[0]); err == nil {
+			_, title, _ := strings.Cut(strings.TrimSpace(article), "\n")
+			if title = strings.TrimSpace(title); len(title) != 0 {
+				d.title = title
+			}
+		}
+	}
+	return d, nil
+}
+
+// FindDictds gives every .index file in dir
+func FindDictds(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*.index"))
+}
+
+// readIndex parses lines of "headword\toffset\tlength" with dictd base64 numbers
+func (d *Dictd) readIndex(indexPath string) error {
+	f, err := os.Open(filepath.Clean(indexPath))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 {
+			return fmt.Errorf("%s:%d: corrupted dictd index", indexPath, line)
+		}
+		offset, err := decodeDictdBase64(fields[1])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", indexPath, line, err)
+		}
+		size, err := decodeDictdBase64(fields[2])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", indexPath, line, err)
+		}
+		key := foldDictdHeadword(fields[0])
+		if _, ok := d.entries[key]; !ok {
+			d.headwords = append(d.headwords, key)
+		}
+		d.entries[key] = append(d.entries[key], dictdEntry{headword: fields[0], offset: offset, size: size})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	sort.Strings(d.headwords)
+	return nil
+}
+
+func decodeDictdBase64(s string) (int64, error) {
+	var n int64
+	for _, c := range s {
+		digit := strings.IndexRune(dictdBase64, c)
+		if digit < 0 {
+			return 0, fmt.Errorf("invalid dictd base64 number: %s", s)
+		}
+		n = n*64 + int64(digit)
+	}
+	return n, nil
+}
+
+// foldDictdHeadword ignores case and surrounding spaces like dictd does by default
+func foldDictdHeadword(word string) string {
+	return strings.ToLower(strings.TrimSpace(re.ReplaceAllString(word, " ")))
+}
+
+func (d *Dictd) article(entry dictdEntry) (string, error) {
+	data := make([]byte, entry.size)
+	if _, err := d.dict.ReadAt(data, entry.offset); err != nil && err != io.EOF {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (d *Dictd) Search(ctx context.Context, word string) ([]entity.Definition, error) {
+	result := make([]entity.Definition, 0, 3)
+	for _, entry := range d.entries[foldDictdHeadword(word)] {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		article, err := d.article(entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, definition := range splitDictdArticle(entry.headword, article) {
+			definition.Source = d.Name
+			definition.Sense = len(result) + 1
+			result = append(result, definition)
+		}
+	}
+	if len(result) == 0 {
+		return result, ErrorNoDef
+	}
+	return result, nil
+}
+
+// splitDictdArticle turns numbered senses and paragraphs of article into definitions,
+// the first line repeating headword is skipped
+func splitDictdArticle(headword, article string) []entity.Definition {
+	definitions := make([]entity.Definition, 0)
+	lines := strings.Split(article, "\n")
+	if len(lines) != 0 && strings.EqualFold(strings.TrimSpace(lines[0]), strings.TrimSpace(headword)) {
+		lines = lines[1:]
+	}
+	var pos string
+	var current []string
+	flush := func() {
+		text := strings.TrimSpace(re.ReplaceAllString(strings.Join(current, " "), " "))
+		if len(text) != 0 {
+			definitions = append(definitions, entity.Definition{Text: text, PartOfSpeech: pos})
+		}
+		current = current[:0]
+	}
+	for _, line := range lines {
+		if len(strings.TrimSpace(line)) == 0 {
+			flush()
+			continue
+		}
+		if match := dictdSenseRe.FindStringSubmatch(line); match != nil {
+			flush()
+			if len(match[1]) != 0 {
+				pos = match[1]
+			}
+			line = line[len(match[0]):]
+		}
+		current = append(current, line)
+	}
+	flush()
+	return definitions
+}
+
+// Match implements Matcher
+func (d *Dictd) Match(ctx context.Context, strategy, word string) ([]string, error) {
+	return matchSorted(ctx, d.headwords, func(idx int) string {
+		return d.entries[d.headwords[idx]][0].headword
+	}, strategy, foldDictdHeadword(word))
+}
+
+// Language guesses whether headwords are Cyrillic
+func (d *Dictd) Language() entity.DictionaryLanguage {
+	if len(d.headwords) == 0 {
+		return entity.English
+	}
+	return guessLanguage(d.headwords[len(d.headwords)/2])
+}
+
+// Title is the short description of database
+func (d *Dictd) Title() string {
+	return d.title
+}
+
+func (d *Dictd) GetName() string {
+	return d.Name
+}
+
+func (d *Dictd) Close() error {
+	return d.dict.Close()
+}
+
+// guessLanguage tells Russian from English by whether word has any Cyrillic letter
+func guessLanguage(word string) entity.DictionaryLanguage {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return entity.Russian
+		}
+	}
+	return entity.English
+}
@@ -1,14 +1,17 @@
 package dictionary
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly/v2"
+	"github.com/s8508235/tui-dictionary/pkg/dictproto"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/net/dict"
 )
 
 var re = regexp.MustCompile(`(?s)[\s]+`)
@@ -47,10 +50,13 @@ func replaceSpaceWithASCII(word string) string {
 	return re.ReplaceAllString(word, "%20")
 }
 
-func generalWebDictionarySearch(results *[]string, counter *int) func(e *colly.HTMLElement) {
+func generalWebDictionarySearch(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement) {
 	return func(e *colly.HTMLElement) {
 		// if *counter < 3 {
-		*results = append(*results, e.Text)
+		*results = append(*results, entity.Definition{
+			Text:  e.Text,
+			Sense: *counter + 1,
+		})
 		// } else {
 		// 	return
 		// }
@@ -58,6 +64,23 @@ func generalWebDictionarySearch(results *[]string, counter *int) func(e *colly.H
 	}
 }
 
+// selectionTexts collects trimmed and non-empty text of each node in selection
+func selectionTexts(s *goquery.Selection) []string {
+	texts := make([]string, 0, s.Length())
+	s.Each(func(_ int, n *goquery.Selection) {
+		text := strings.TrimSpace(re.ReplaceAllString(n.Text(), " "))
+		if len(text) != 0 {
+			texts = append(texts, text)
+		}
+	})
+	return texts
+}
+
+// firstText gives trimmed text of first node in selection
+func firstText(s *goquery.Selection) string {
+	return strings.TrimSpace(re.ReplaceAllString(s.First().Text(), " "))
+}
+
 // removeRussianAccentMarks remove stress in Russian but this is not best strategy
 // https://russianalphabet.online/stress-marks-in-russian/
 func removeRussianAccentMarks(word string) string {
@@ -69,18 +92,22 @@ func removeRussianAccentMarks(word string) string {
 	}, word)
 }
 
-func NewDICTClient(logger *log.Logger, network, addr, prefix string) (*DICTClient, error) {
-	client, err := dict.Dial(network, addr)
-	if err != nil {
+// NewDICTClient connects to a DICT server searching databases in order, "!" by default
+func NewDICTClient(logger *log.Logger, network, addr string, databases ...string) (*DICTClient, error) {
+	pool := dictproto.NewPool(logger, network, addr, dictproto.DefaultKeepAlive)
+	// the first connection tells if server is reachable at all
+	if err := pool.Do(context.Background(), func(*dictproto.Conn) error { return nil }); err != nil {
+		_ = pool.Close()
 		return nil, err
 	}
-
+	if len(databases) == 0 {
+		databases = []string{"!"}
+	}
 	return &DICTClient{
-		network:          network,
-		addr:             addr,
-		Client:           client,
-		Logger:           logger,
-		DictionaryPrefix: prefix,
+		pool:      pool,
+		Logger:    logger,
+		Databases: databases,
+		Limit:     DefaultDICTLimit,
 	}, nil
 }
 
@@ -97,7 +124,7 @@ func NewUrbanDictionary(logger *log.Logger) (Interface, error) {
 			return fmt.Sprintf(urbanURL, replaceSpaceWithASCII(word))
 		},
 		Selector:   urbanSelector,
-		SearchFunc: generalWebDictionarySearch,
+		SearchFunc: urbanSearch,
 		Name:       "urban",
 	}, nil
 }
@@ -115,7 +142,7 @@ func NewLearnerDictionary(logger *log.Logger) (Interface, error) {
 			return fmt.Sprintf(learnerURL, re.ReplaceAllString(word, "-"))
 		},
 		Selector:   learnerSelector,
-		SearchFunc: generalWebDictionarySearch,
+		SearchFunc: learnerSearch,
 		Name:       "britannica",
 	}, nil
 }
@@ -151,7 +178,7 @@ func NewCambridgeDictionary(logger *log.Logger) (Interface, error) {
 			return fmt.Sprintf(cambridgeURL, re.ReplaceAllString(word, "-"))
 		},
 		Selector:   cambridgeSelector,
-		SearchFunc: generalWebDictionarySearch,
+		SearchFunc: cambridgeSearch,
 		Name:       "cambridge",
 	}, nil
 }
@@ -169,12 +196,12 @@ func NewOxfordLearnerDictionary(logger *log.Logger) (Interface, error) {
 			return fmt.Sprintf(oxfordURL, re.ReplaceAllString(word, "-"), re.ReplaceAllString(word, "+"))
 		},
 		Selector:   oxfordSelector,
-		SearchFunc: generalWebDictionarySearch,
+		SearchFunc: oxfordSearch,
 		Name:       "oxford-learner",
 	}, nil
 }
 
-func NewMyPreferDictionary(logger *log.Logger) (Interface, error) {
+func NewMyPreferDictionary(logger *log.Logger) (*MyPrefer, error) {
 	oxford, err := NewOxfordLearnerDictionary(logger)
 	if err != nil {
 		return nil, err
@@ -193,11 +220,13 @@ func NewMyPreferDictionary(logger *log.Logger) (Interface, error) {
 	}
 	dictionaries := []Interface{oxford, cambridge, webster, learner}
 	return &MyPrefer{
+		// eng-prefer has been the name of the one with Urban
+		Name:         "eng-plain-prefer",
 		Dictionaries: dictionaries,
 	}, nil
 }
 
-func NewMyPreferWithUrbanDictionary(logger *log.Logger) (Interface, error) {
+func NewMyPreferWithUrbanDictionary(logger *log.Logger) (*MyPrefer, error) {
 	oxford, err := NewOxfordLearnerDictionary(logger)
 	if err != nil {
 		return nil, err
@@ -308,3 +337,29 @@ func NewMyPreferRUDictionary(logger *log.Logger) (*MyPrefer, error) {
 		Dictionaries: dictionaries,
 	}, nil
 }
+
+// NewAllDictionaries gives every web dictionary and the aggregated ones, each with a unique name
+func NewAllDictionaries(logger *log.Logger) ([]Interface, error) {
+	constructors := []func(*log.Logger) (Interface, error){
+		NewOxfordLearnerDictionary,
+		NewCambridgeDictionary,
+		NewWebsterDictionary,
+		NewLearnerDictionary,
+		NewUrbanDictionary,
+		NewDictComRussianEnglishDictionary,
+		NewRussianDictDictionary,
+		NewOpenRussianDictionary,
+		func(logger *log.Logger) (Interface, error) { return NewMyPreferDictionary(logger) },
+		func(logger *log.Logger) (Interface, error) { return NewMyPreferWithUrbanDictionary(logger) },
+		func(logger *log.Logger) (Interface, error) { return NewMyPreferRUDictionary(logger) },
+	}
+	dictionaries := make([]Interface, 0, len(constructors))
+	for _, constructor := range constructors {
+		dictionary, err := constructor(logger)
+		if err != nil {
+			return nil, err
+		}
+		dictionaries = append(dictionaries, dictionary)
+	}
+	return dictionaries, nil
+}
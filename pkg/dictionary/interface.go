@@ -1,10 +1,34 @@
 package dictionary
 
-import "errors"
+import (
+	"context"
+	"errors"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
 
 var ErrorNoDef = errors.New("no definition found")
 
 type Interface interface {
-	Search(word string) ([]string, error)
+	// Search should stop any network work once ctx is done and return ctx.Err()
+	Search(ctx context.Context, word string) ([]entity.Definition, error)
 	GetName() string
 }
+
+// SourceResult is the answer of one dictionary in a streaming search
+type SourceResult struct {
+	// Index is the position of the dictionary in Sources()
+	Index       int
+	Source      string
+	Definitions []entity.Definition
+	Err         error
+}
+
+// Streamer is an aggregated dictionary which sends every dictionary's result as soon as it is ready
+type Streamer interface {
+	Interface
+	Sources() []string
+	SearchStream(ctx context.Context, word string) <-chan SourceResult
+	// Arrange gives the definitions of received results in a stable order
+	Arrange(results []SourceResult) []entity.Definition
+}
@@ -1,6 +1,25 @@
 package dictionary
 
+import (
+	"github.com/gocolly/colly/v2"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
+
 const (
 	learnerURL      = "https://www.britannica.com/dictionary/%s"
 	learnerSelector = "div.entry div.sblock_entry span.def_text"
 )
+
+func learnerSearch(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement) {
+	return func(e *colly.HTMLElement) {
+		sense := e.DOM.Closest("div.sblock_entry")
+		*results = append(*results, entity.Definition{
+			Text:         e.Text,
+			PartOfSpeech: firstText(e.DOM.Closest("div.entry").Find("span.fl")),
+			Examples:     selectionTexts(sense.Find("li.vi")),
+			Labels:       selectionTexts(sense.Find("span.sl, span.gram")),
+			Sense:        *counter + 1,
+		})
+		*counter += 1
+	}
+}
@@ -0,0 +1,78 @@
+package dictionary
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownStrategy = errors.New("unknown match strategy")
+
+// MatchStrategies are the strategies supported by local dictionaries, see MATCH in RFC 2229
+var MatchStrategies = map[string]string{
+	"exact":  "Match headwords exactly",
+	"prefix": "Match prefixes",
+	"lev":    "Match headwords within Levenshtein distance one",
+}
+
+// Matcher is a dictionary able to list its headwords matching word by strategy
+type Matcher interface {
+	Match(ctx context.Context, strategy, word string) ([]string, error)
+}
+
+// matchSorted finds headwords in sorted and folded headwords, original gives the headword to show
+func matchSorted(ctx context.Context, headwords []string, original func(int) string, strategy, word string) ([]string, error) {
+	word = strings.ToLower(word)
+	matches := make([]string, 0)
+	switch strategy {
+	case "exact", "prefix":
+		start := sort.SearchStrings(headwords, word)
+		for idx := start; idx < len(headwords); idx++ {
+			if headwords[idx] != word && (strategy == "exact" || !strings.HasPrefix(headwords[idx], word)) {
+				break
+			}
+			matches = append(matches, original(idx))
+		}
+	case "lev":
+		for idx, headword := range headwords {
+			if idx%1024 == 0 && ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if withinOneEdit(headword, word) {
+				matches = append(matches, original(idx))
+			}
+		}
+	default:
+		return nil, ErrUnknownStrategy
+	}
+	return matches, nil
+}
+
+// withinOneEdit tells if a can become b by at most one insertion, deletion or substitution
+func withinOneEdit(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(ra)-len(rb) > 1 {
+		return false
+	}
+	i, j, edits := 0, 0, 0
+	for i < len(ra) && j < len(rb) {
+		if ra[i] == rb[j] {
+			i++
+			j++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		i++
+		if len(ra) == len(rb) {
+			j++
+		}
+	}
+	return edits+(len(ra)-i) <= 1
+}
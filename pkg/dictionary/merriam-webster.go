@@ -2,6 +2,7 @@ package dictionary
 
 import (
 	"github.com/gocolly/colly/v2"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
 )
 
 const (
@@ -9,11 +10,18 @@ const (
 	websterURLSelector = "div.sb span.dt span.dtText"
 )
 
-func websterSearch(results *[]string, counter *int) func(e *colly.HTMLElement) {
+func websterSearch(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement) {
 	return func(e *colly.HTMLElement) {
 		result := e.DOM.ReplaceWithSelection(e.DOM.ChildrenFiltered("strong.mw_t_bc")).Text()
 		if *counter < 3 {
-			*results = append(*results, result)
+			sense := e.DOM.Closest("div.sense")
+			*results = append(*results, entity.Definition{
+				Text:         result,
+				PartOfSpeech: firstText(e.DOM.Closest("div.entry-word-section-container").Find("h2.parts-of-speech")),
+				Examples:     selectionTexts(sense.Find("span.ex-sent")),
+				Labels:       selectionTexts(sense.Find("span.sl, span.lb")),
+				Sense:        *counter + 1,
+			})
 		} else {
 			return
 		}
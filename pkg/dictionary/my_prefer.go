@@ -1,57 +1,194 @@
 package dictionary
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
-	"golang.org/x/sync/errgroup"
+	"github.com/s8508235/tui-dictionary/pkg/cache"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	log "github.com/sirupsen/logrus"
 )
 
 type MyPrefer struct {
 	Name         string
 	Dictionaries []Interface
+	// Timeout bounds the search of each dictionary, zero means no limit
+	Timeout time.Duration
+	// Dedupe folds the same definitions from different dictionaries into one
+	Dedupe bool
+	// Similarity is the token overlap for Dedupe, zero means DefaultSimilarity
+	Similarity float64
+	// Fallback is asked when no dictionary gives a definition and some of them failed
+	Fallback Interface
 }
 
-func (m *MyPrefer) Search(word string) ([]string, error) {
-	eg := new(errgroup.Group)
+// SourceError tells which dictionary failed in an aggregated search
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return fmt.Sprintf("%s timed out", e.Source)
+	}
+	return fmt.Sprintf("%s failed: %s", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError comes along with the definitions from the dictionaries that succeeded
+type PartialError struct {
+	Failures []*SourceError
+}
+
+func (e *PartialError) Error() string {
+	messages := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		messages = append(messages, failure.Error())
+	}
+	return strings.Join(messages, ", ")
+}
+
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		errs = append(errs, failure)
+	}
+	return errs
+}
+
+// Search asks every dictionary and keeps whatever succeeded,
+// a *PartialError lists the failed dictionaries if there is any
+func (m *MyPrefer) Search(ctx context.Context, word string) ([]entity.Definition, error) {
+	results := make([]SourceResult, 0, len(m.Dictionaries))
+	// indexed by dictionary so failures keep the configured order
+	failures := make([]*SourceError, len(m.Dictionaries)+1)
+	for res := range m.SearchStream(ctx, word) {
+		if res.Err != nil && res.Err != ErrorNoDef {
+			failures[res.Index] = &SourceError{Source: res.Source, Err: res.Err}
+			continue
+		}
+		results = append(results, res)
+	}
+	result := m.Arrange(results)
+	// the whole search is cancelled rather than some dictionaries
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	partial := &PartialError{}
+	for _, failure := range failures {
+		if failure != nil {
+			partial.Failures = append(partial.Failures, failure)
+		}
+	}
+	if len(partial.Failures) != 0 {
+		return result, partial
+	}
+	if len(result) == 0 {
+		return result, ErrorNoDef
+	}
+	return result, nil
+}
+
+// SearchStream sends the result of each dictionary once it finishes,
+// the channel is closed after every dictionary answered.
+// The result of Fallback comes last with the index after all dictionaries.
+func (m *MyPrefer) SearchStream(ctx context.Context, word string) <-chan SourceResult {
 	var wg sync.WaitGroup
-	resultChan := make(chan string)
-	result := make([]string, 0, 5)
-	for _, dictionary := range m.Dictionaries {
-		dictionary := dictionary
+	var mu sync.Mutex
+	found, failed := false, false
+	resultChan := make(chan SourceResult, len(m.Dictionaries)+1)
+	for idx, dictionary := range m.Dictionaries {
+		idx, dictionary := idx, dictionary
 		wg.Add(1)
-		eg.Go(func() error {
+		go func() {
 			defer wg.Done()
-			r, err := dictionary.Search(word)
-			if err != nil && err != ErrorNoDef {
-				return err
+			r, err := m.searchSource(ctx, dictionary, word)
+			for i := range r {
+				r[i].Text = re.ReplaceAllString(r[i].Text, " ")
+				r[i].Text = strings.TrimSpace(r[i].Text)
 			}
-			for _, res := range r {
-				res = re.ReplaceAllString(res, " ")
-				res = strings.TrimSpace(res)
-				resultChan <- res
+			mu.Lock()
+			found = found || len(r) != 0
+			failed = failed || (err != nil && err != ErrorNoDef)
+			mu.Unlock()
+			resultChan <- SourceResult{
+				Index:       idx,
+				Source:      dictionary.GetName(),
+				Definitions: r,
+				Err:         err,
 			}
-			return nil
-		})
+		}()
 	}
-	eg.Go(func() error {
+	go func() {
 		wg.Wait()
+		if m.Fallback != nil && !found && failed && ctx.Err() == nil {
+			r, err := m.Fallback.Search(ctx, word)
+			resultChan <- SourceResult{
+				Index:       len(m.Dictionaries),
+				Source:      m.Fallback.GetName(),
+				Definitions: r,
+				Err:         err,
+			}
+		}
 		close(resultChan)
-		return nil
+	}()
+	return resultChan
+}
+
+func (m *MyPrefer) searchSource(ctx context.Context, dictionary Interface, word string) ([]entity.Definition, error) {
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+	return dictionary.Search(ctx, word)
+}
+
+// Arrange groups definitions by dictionary in configured order no matter which one answers first,
+// and folds duplicates if Dedupe is on
+func (m *MyPrefer) Arrange(results []SourceResult) []entity.Definition {
+	sorted := make([]SourceResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Index < sorted[j].Index
 	})
-	eg.Go(func() error {
-		for definitions := range resultChan {
-			result = append(result, definitions)
+	arranged := make([]entity.Definition, 0, 5)
+	for _, res := range sorted {
+		arranged = append(arranged, res.Definitions...)
+	}
+	if m.Dedupe {
+		similarity := m.Similarity
+		if similarity == 0 {
+			similarity = DefaultSimilarity
 		}
-		return nil
-	})
-	if err := eg.Wait(); err != nil {
-		return result, err
+		return Dedupe(arranged, similarity)
 	}
-	if len(result) == 0 {
-		return result, ErrorNoDef
+	return arranged
+}
+
+// UseCache puts every dictionary behind c so each of them is cached by its own name
+func (m *MyPrefer) UseCache(logger *log.Logger, c *cache.Cache) {
+	for idx, dictionary := range m.Dictionaries {
+		m.Dictionaries[idx] = NewCachedDictionary(logger, dictionary, c)
 	}
-	return result, nil
+}
+
+// Sources gives names of the dictionaries in configured order
+func (m *MyPrefer) Sources() []string {
+	sources := make([]string, 0, len(m.Dictionaries))
+	for _, dictionary := range m.Dictionaries {
+		sources = append(sources, dictionary.GetName())
+	}
+	return sources
 }
 
 func (m *MyPrefer) GetName() string {
@@ -0,0 +1,90 @@
+package dictionary
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/s8508235/tui-dictionary/pkg/cache"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	log "github.com/sirupsen/logrus"
+)
+
+// OfflineDictionary answers from lookup cache and saved target files without network
+type OfflineDictionary struct {
+	Cache *cache.Cache
+	// Targets are tab-separated files written by the TUI
+	Targets []string
+	Logger  *log.Logger
+}
+
+func NewOfflineDictionary(logger *log.Logger, c *cache.Cache, targets []string) *OfflineDictionary {
+	return &OfflineDictionary{
+		Cache:   c,
+		Targets: targets,
+		Logger:  logger,
+	}
+}
+
+func (o *OfflineDictionary) Search(ctx context.Context, word string) ([]entity.Definition, error) {
+	result := make([]entity.Definition, 0, 3)
+	if o.Cache != nil {
+		for _, entry := range o.Cache.Entries(word) {
+			for _, d := range entry.Definitions {
+				d.Offline = true
+				result = append(result, d)
+			}
+		}
+	}
+	for _, target := range o.Targets {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		definitions, err := searchTarget(target, word)
+		if err != nil {
+			o.Logger.Warnln("Fail to read target", target, err)
+			continue
+		}
+		result = append(result, definitions...)
+	}
+	if len(result) == 0 {
+		return result, ErrorNoDef
+	}
+	return result, nil
+}
+
+// searchTarget finds the definitions of word in lines like "word\tdef1;def2"
+func searchTarget(target, word string) ([]entity.Definition, error) {
+	f, err := os.Open(filepath.Clean(target))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	result := make([]entity.Definition, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		savedWord, definitions, found := strings.Cut(scanner.Text(), "\t")
+		if !found || !strings.EqualFold(strings.TrimSpace(savedWord), word) {
+			continue
+		}
+		for _, definition := range strings.Split(definitions, ";") {
+			definition = strings.TrimSpace(definition)
+			if len(definition) == 0 {
+				continue
+			}
+			result = append(result, entity.Definition{
+				Text:    definition,
+				Source:  filepath.Base(target),
+				Sense:   len(result) + 1,
+				Offline: true,
+			})
+		}
+	}
+	return result, scanner.Err()
+}
+
+func (o *OfflineDictionary) GetName() string {
+	return "offline"
+}
@@ -2,6 +2,7 @@ package dictionary
 
 import (
 	"github.com/gocolly/colly/v2"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -13,6 +14,20 @@ const (
 type oxfordCrawler struct {
 	Selector   string
 	SearchURL  func(string) string
-	SearchFunc func(results *[]string, counter *int) func(e *colly.HTMLElement)
+	SearchFunc func(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement)
 	Logger     *log.Logger
 }
+
+func oxfordSearch(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement) {
+	return func(e *colly.HTMLElement) {
+		sense := e.DOM.Closest("li.sense")
+		*results = append(*results, entity.Definition{
+			Text:         e.Text,
+			PartOfSpeech: firstText(e.DOM.Closest("div.entry").Find("div.webtop span.pos")),
+			Examples:     selectionTexts(sense.Find("ul.examples li span.x")),
+			Labels:       selectionTexts(sense.ChildrenFiltered("span.labels, span.grammar, span.use")),
+			Sense:        *counter + 1,
+		})
+		*counter += 1
+	}
+}
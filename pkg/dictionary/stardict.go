@@ -0,0 +1,339 @@
+package dictionary
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictzip"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	log "github.com/sirupsen/logrus"
+)
+
+var markupRe = regexp.MustCompile(`<[^>]*>`)
+
+type stardictEntry struct {
+	word   string
+	offset int64
+	size   int64
+}
+
+// StarDict reads a StarDict dictionary (.ifo, .idx and .dict.dz) from disk
+type StarDict struct {
+	Name string
+	// Info is the key value pairs in .ifo like bookname or wordcount
+	Info             map[string]string
+	Logger           *log.Logger
+	sameTypeSequence string
+	entries          []stardictEntry
+	dict             *dictzip.Reader
+	// folded are lower case headwords sorted for Match and foldedOrder are their entry index
+	foldOnce    sync.Once
+	folded      []string
+	foldedOrder []int
+}
+
+// NewStarDictDictionary loads the whole index of ifoPath into memory,
+// .idx(.gz) and .dict(.dz) are looked up next to it
+func NewStarDictDictionary(logger *log.Logger, ifoPath string) (*StarDict, error) {
+	info, err := readStarDictInfo(ifoPath)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(ifoPath, filepath.Ext(ifoPath))
+	offsetBits := 32
+	if info["idxoffsetbits"] == "64" {
+		offsetBits = 64
+	}
+	entries, err := readStarDictIndex(base, offsetBits)
+	if err != nil {
+		return nil, err
+	}
+	var dict *dictzip.Reader
+	for _, path := range []string{base + ".dict.dz", base + ".dict"} {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if dict, err = dictzip.Open(path); err != nil {
+			return nil, err
+		}
+		break
+	}
+	if dict == nil {
+		return nil, fmt.Errorf("no .dict or .dict.dz for %s", ifoPath)
+	}
+	return &StarDict{
+		Name:             filepath.Base(base),
+		Info:             info,
+		Logger:           logger,
+		sameTypeSequence: info["sametypesequence"],
+		entries:          entries,
+		dict:             dict,
+	}, nil
+}
+
+// FindStarDicts gives every .ifo file in dir
+func FindStarDicts(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*.ifo"))
+}
+
+func readStarDictInfo(ifoPath string) (map[string]string, error) {
+	f, err := os.Open(filepath.Clean(ifoPath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if found {
+			info[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := info["version"]; !ok {
+		return nil, fmt.Errorf("%s is not a StarDict ifo file", ifoPath)
+	}
+	return info, nil
+}
+
+// readStarDictIndex parses entries of "word\0" with big endian offset and size into the data file
+func readStarDictIndex(base string, offsetBits int) ([]stardictEntry, error) {
+	var data []byte
+	var err error
+	if data, err = os.ReadFile(filepath.Clean(base + ".idx")); os.IsNotExist(err) {
+		f, err := os.Open(filepath.Clean(base + ".idx.gz"))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	offsetSize := offsetBits / 8
+	entries := make([]stardictEntry, 0)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 || len(data) < end+1+offsetSize+4 {
+			return nil, fmt.Errorf("corrupted StarDict index %s.idx", base)
+		}
+		entry := stardictEntry{word: string(data[:end])}
+		data = data[end+1:]
+		if offsetSize == 8 {
+			entry.offset = int64(binary.BigEndian.Uint64(data))
+		} else {
+			entry.offset = int64(binary.BigEndian.Uint32(data))
+		}
+		entry.size = int64(binary.BigEndian.Uint32(data[offsetSize:]))
+		data = data[offsetSize+4:]
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// stardictCompare is the order of StarDict index, ASCII case-insensitive first and then byte-wise
+func stardictCompare(a, b string) int {
+	if c := asciiFoldCompare(a, b); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func asciiFoldCompare(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ca, cb := asciiLower(a[i]), asciiLower(b[i])
+		if ca != cb {
+			if ca < cb {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+func asciiLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// lookup gives the entries equal to word ignoring ASCII case by binary search
+func (s *StarDict) lookup(word string) []stardictEntry {
+	start := sort.Search(len(s.entries), func(i int) bool {
+		return asciiFoldCompare(s.entries[i].word, word) >= 0
+	})
+	end := start
+	for end < len(s.entries) && asciiFoldCompare(s.entries[end].word, word) == 0 {
+		end++
+	}
+	return s.entries[start:end]
+}
+
+func (s *StarDict) Search(ctx context.Context, word string) ([]entity.Definition, error) {
+	result := make([]entity.Definition, 0, 3)
+	for _, entry := range s.lookup(word) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		data := make([]byte, entry.size)
+		if _, err := s.dict.ReadAt(data, entry.offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		for _, text := range s.parseData(data) {
+			for _, line := range strings.Split(text, "\n") {
+				line = strings.TrimSpace(line)
+				if len(line) == 0 {
+					continue
+				}
+				result = append(result, entity.Definition{
+					Text:   line,
+					Source: s.Name,
+					Sense:  len(result) + 1,
+				})
+			}
+		}
+	}
+	if len(result) == 0 {
+		return result, ErrorNoDef
+	}
+	return result, nil
+}
+
+// parseData splits an article into text fields, see "sametypesequence" in StarDict format doc:
+// a lower case type is a \0 terminated string and an upper case one is prefixed by its 32 bits size
+func (s *StarDict) parseData(data []byte) []string {
+	texts := make([]string, 0, 1)
+	types := s.sameTypeSequence
+	for idx := 0; len(data) > 0; idx++ {
+		var fieldType byte
+		if len(types) != 0 {
+			if idx >= len(types) {
+				break
+			}
+			fieldType = types[idx]
+		} else {
+			fieldType, data = data[0], data[1:]
+		}
+		// the last field of sametypesequence has no terminator or size
+		last := len(types) != 0 && idx == len(types)-1
+		var field []byte
+		switch {
+		case last:
+			field, data = data, nil
+		case unicode.IsLower(rune(fieldType)):
+			end := bytes.IndexByte(data, 0)
+			if end < 0 {
+				field, data = data, nil
+			} else {
+				field, data = data[:end], data[end+1:]
+			}
+		default:
+			if len(data) < 4 {
+				return texts
+			}
+			size := int(binary.BigEndian.Uint32(data))
+			if len(data) < 4+size {
+				return texts
+			}
+			field, data = data[4:4+size], data[4+size:]
+		}
+		if text := stardictText(fieldType, field); len(text) != 0 {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// stardictText keeps the readable text of a field, markups are stripped
+func stardictText(fieldType byte, field []byte) string {
+	switch fieldType {
+	case 'm', 'l', 'y':
+		return string(field)
+	case 'g', 'x', 'h', 'w', 'k':
+		text := strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n", "</div>", "\n").Replace(string(field))
+		return html.UnescapeString(markupRe.ReplaceAllString(text, ""))
+	default:
+		// phonetic, resources and binary data
+		return ""
+	}
+}
+
+// Match implements Matcher, headwords are compared in lower case
+func (s *StarDict) Match(ctx context.Context, strategy, word string) ([]string, error) {
+	s.foldOnce.Do(func() {
+		s.folded = make([]string, len(s.entries))
+		s.foldedOrder = make([]int, len(s.entries))
+		for idx, entry := range s.entries {
+			s.foldedOrder[idx] = idx
+			s.folded[idx] = strings.ToLower(entry.word)
+		}
+		sort.Sort(foldedIndex{s})
+	})
+	return matchSorted(ctx, s.folded, func(idx int) string {
+		return s.entries[s.foldedOrder[idx]].word
+	}, strategy, word)
+}
+
+// foldedIndex sorts lower case headwords along with their entry index
+type foldedIndex struct{ s *StarDict }
+
+func (f foldedIndex) Len() int           { return len(f.s.folded) }
+func (f foldedIndex) Less(i, j int) bool { return f.s.folded[i] < f.s.folded[j] }
+func (f foldedIndex) Swap(i, j int) {
+	f.s.folded[i], f.s.folded[j] = f.s.folded[j], f.s.folded[i]
+	f.s.foldedOrder[i], f.s.foldedOrder[j] = f.s.foldedOrder[j], f.s.foldedOrder[i]
+}
+
+// Language guesses whether headwords are Cyrillic
+func (s *StarDict) Language() entity.DictionaryLanguage {
+	if len(s.entries) == 0 {
+		return entity.English
+	}
+	return guessLanguage(s.entries[len(s.entries)/2].word)
+}
+
+// Title is the book name of dictionary
+func (s *StarDict) Title() string {
+	if title, ok := s.Info["bookname"]; ok {
+		return title
+	}
+	return s.Name
+}
+
+func (s *StarDict) GetName() string {
+	return s.Name
+}
+
+func (s *StarDict) Close() error {
+	return s.dict.Close()
+}
@@ -1,6 +1,23 @@
 package dictionary
 
+import (
+	"github.com/gocolly/colly/v2"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
+
 const (
 	urbanURL      = "https://www.urbandictionary.com/define.php?term=%s"
 	urbanSelector = "div.definition div.meaning"
 )
+
+func urbanSearch(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement) {
+	return func(e *colly.HTMLElement) {
+		*results = append(*results, entity.Definition{
+			Text:     e.Text,
+			Examples: selectionTexts(e.DOM.Closest("div.definition").Find("div.example")),
+			Labels:   []string{"slang"},
+			Sense:    *counter + 1,
+		})
+		*counter += 1
+	}
+}
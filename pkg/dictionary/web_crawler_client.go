@@ -1,26 +1,31 @@
 package dictionary
 
 import (
+	"context"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/extensions"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
 )
 
 type WebDictionaryCrawler struct {
 	Selector   string
 	SearchURL  func(string) string
-	SearchFunc func(results *[]string, counter *int) func(e *colly.HTMLElement)
+	SearchFunc func(results *[]entity.Definition, counter *int) func(e *colly.HTMLElement)
 	Crawler    *colly.Collector
 	Logger     *log.Logger
 	Name       string
 }
 
-func (c *WebDictionaryCrawler) Search(word string) ([]string, error) {
+func (c *WebDictionaryCrawler) Search(ctx context.Context, word string) ([]entity.Definition, error) {
 	crawler := c.Crawler.Clone()
+	// requests made by the clone are bound to ctx so cancel aborts the visit
+	crawler.Context = ctx
 	// https://github.com/gocolly/colly/issues/150
 	extensions.RandomUserAgent(crawler)
-	result := make([]string, 0, 3)
+	result := make([]entity.Definition, 0, 3)
 	count := 0
 
 	crawler.OnHTML(c.Selector, c.SearchFunc(&result, &count))
@@ -38,6 +43,12 @@ func (c *WebDictionaryCrawler) Search(word string) ([]string, error) {
 	})
 
 	err := crawler.Visit(c.SearchURL(word))
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	for idx := range result {
+		result[idx].Source = c.Name
+	}
 
 	if len(result) == 0 {
 		return result, ErrorNoDef
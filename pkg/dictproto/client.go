@@ -0,0 +1,214 @@
+package dictproto
+
+import (
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+var (
+	ErrNoMatch         = errors.New("no match")
+	ErrInvalidDatabase = errors.New("invalid database")
+	ErrInvalidStrategy = errors.New("invalid strategy")
+)
+
+// Definition is one article of DEFINE response
+type Definition struct {
+	Word        string
+	Database    string
+	Description string
+	Text        string
+}
+
+// Match is one headword of MATCH response
+type Match struct {
+	Database string
+	Word     string
+}
+
+// Item is a database or strategy with its description from SHOW DB or SHOW STRAT
+type Item struct {
+	Name        string
+	Description string
+}
+
+// Conn is a client connection to a DICT server, it is not safe for concurrent use
+type Conn struct {
+	conn net.Conn
+	text *textproto.Conn
+}
+
+// Dial connects to a DICT server and reads its banner
+func Dial(network, addr string) (*Conn, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn)
+}
+
+// NewConn reads the banner from a connected DICT server
+func NewConn(conn net.Conn) (*Conn, error) {
+	c := &Conn{conn: conn, text: textproto.NewConn(conn)}
+	if _, _, err := c.text.ReadCodeLine(220); err != nil {
+		_ = c.text.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NetConn gives the underlying connection to set deadlines on
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// cmd sends a command and reads its status line
+func (c *Conn) cmd(format string, args ...interface{}) (int, string, error) {
+	id, err := c.text.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	c.text.StartResponse(id)
+	defer c.text.EndResponse(id)
+	return c.text.ReadCodeLine(0)
+}
+
+// statusError maps error status of a command to errors of this package
+func statusError(code int, msg string) error {
+	switch code {
+	case 552:
+		return ErrNoMatch
+	case 550:
+		return ErrInvalidDatabase
+	case 551:
+		return ErrInvalidStrategy
+	default:
+		return &textproto.Error{Code: code, Msg: msg}
+	}
+}
+
+// readOK reads the final status line of a successful command
+func (c *Conn) readOK() error {
+	_, _, err := c.text.ReadCodeLine(250)
+	return err
+}
+
+// Define looks up word in database, "*" searches all databases and "!" stops at the first one having word
+func (c *Conn) Define(database, word string) ([]Definition, error) {
+	code, msg, err := c.cmd("DEFINE %s %s", database, Quote(word))
+	if err != nil {
+		return nil, err
+	}
+	if code != 150 {
+		return nil, statusError(code, msg)
+	}
+	definitions := make([]Definition, 0)
+	for {
+		code, msg, err := c.text.ReadCodeLine(0)
+		if err != nil {
+			return nil, err
+		}
+		if code == 250 {
+			return definitions, nil
+		}
+		if code != 151 {
+			return nil, statusError(code, msg)
+		}
+		fields, err := SplitLine(msg)
+		if err != nil || len(fields) < 2 {
+			return nil, textproto.ProtocolError("bad definition header: " + msg)
+		}
+		lines, err := c.text.ReadDotLines()
+		if err != nil {
+			return nil, err
+		}
+		definition := Definition{Word: fields[0], Database: fields[1], Text: strings.Join(lines, "\n")}
+		if len(fields) > 2 {
+			definition.Description = fields[2]
+		}
+		definitions = append(definitions, definition)
+	}
+}
+
+// Match lists headwords of database matching word by strategy, "." is the server default strategy
+func (c *Conn) Match(database, strategy, word string) ([]Match, error) {
+	code, msg, err := c.cmd("MATCH %s %s %s", database, strategy, Quote(word))
+	if err != nil {
+		return nil, err
+	}
+	if code != 152 {
+		return nil, statusError(code, msg)
+	}
+	lines, err := c.readPairs()
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]Match, 0, len(lines))
+	for _, line := range lines {
+		matches = append(matches, Match{Database: line.Name, Word: line.Description})
+	}
+	return matches, nil
+}
+
+// ShowDatabases lists databases of the server
+func (c *Conn) ShowDatabases() ([]Item, error) {
+	return c.show("SHOW DB", 110, 554)
+}
+
+// ShowStrategies lists match strategies of the server
+func (c *Conn) ShowStrategies() ([]Item, error) {
+	return c.show("SHOW STRAT", 111, 555)
+}
+
+func (c *Conn) show(command string, okCode, emptyCode int) ([]Item, error) {
+	code, msg, err := c.cmd(command)
+	if err != nil {
+		return nil, err
+	}
+	if code == emptyCode {
+		return []Item{}, nil
+	}
+	if code != okCode {
+		return nil, statusError(code, msg)
+	}
+	return c.readPairs()
+}
+
+// readPairs reads a text block of "name description" lines and the final status
+func (c *Conn) readPairs() ([]Item, error) {
+	lines, err := c.text.ReadDotLines()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(lines))
+	for _, line := range lines {
+		fields, err := SplitLine(line)
+		if err != nil || len(fields) < 2 {
+			return nil, textproto.ProtocolError("bad line: " + line)
+		}
+		items = append(items, Item{Name: fields[0], Description: fields[1]})
+	}
+	return items, c.readOK()
+}
+
+// Status asks server status, it is also used to keep connection alive
+func (c *Conn) Status() (string, error) {
+	code, msg, err := c.cmd("STATUS")
+	if err != nil {
+		return "", err
+	}
+	if code != 210 {
+		return "", statusError(code, msg)
+	}
+	return msg, nil
+}
+
+// Close says goodbye to server and closes the connection
+func (c *Conn) Close() error {
+	if _, err := c.text.Cmd("QUIT"); err != nil {
+		_ = c.text.Close()
+		return err
+	}
+	return c.text.Close()
+}
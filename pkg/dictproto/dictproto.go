@@ -0,0 +1,58 @@
+// Package dictproto implements the line syntax and client side of DICT protocol (RFC 2229)
+package dictproto
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// Quote wraps s in double quotes escaping quotes and backslashes
+func Quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// SplitLine splits a command or response line into words, quoted by single or double quotes as RFC 2229 allows
+func SplitLine(line string) ([]string, error) {
+	args := make([]string, 0, 4)
+	var current strings.Builder
+	inWord := false
+	var quoteChar rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quoteChar != 0:
+			if r == quoteChar {
+				quoteChar = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quoteChar = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoteChar != 0 || escaped {
+		return nil, ErrUnterminatedQuote
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,212 @@
+package dictproto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultMaxIdle   = 2
+	DefaultKeepAlive = time.Minute
+	DefaultRetries   = 4
+	DefaultBackoff   = 100 * time.Millisecond
+	DefaultMaxDelay  = 5 * time.Second
+)
+
+var ErrPoolClosed = errors.New("dict pool closed")
+
+// Pool keeps connections to a DICT server, it is safe for concurrent use
+type Pool struct {
+	network string
+	addr    string
+	Logger  *log.Logger
+	// MaxIdle is the max number of connections kept open between requests
+	MaxIdle int
+	// Retries is the max number of reconnections for a single request
+	Retries int
+	// Backoff is the delay before the first reconnection, it doubles until MaxDelay
+	Backoff  time.Duration
+	MaxDelay time.Duration
+	mu       sync.Mutex
+	idle     []*Conn
+	closed   bool
+	stop     chan struct{}
+}
+
+// NewPool gives a pool sending STATUS on idle connections every keepAlive, zero disables it
+func NewPool(logger *log.Logger, network, addr string, keepAlive time.Duration) *Pool {
+	p := &Pool{
+		network:  network,
+		addr:     addr,
+		Logger:   logger,
+		MaxIdle:  DefaultMaxIdle,
+		Retries:  DefaultRetries,
+		Backoff:  DefaultBackoff,
+		MaxDelay: DefaultMaxDelay,
+		stop:     make(chan struct{}),
+	}
+	if keepAlive > 0 {
+		go p.keepAlive(keepAlive)
+	}
+	return p
+}
+
+// Addr is the address of server
+func (p *Pool) Addr() string {
+	return p.addr
+}
+
+// IsTemporary tells if err is a broken connection worth reconnecting
+func IsTemporary(err error) bool {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNABORTED):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	default:
+		return false
+	}
+}
+
+func (p *Pool) get(ctx context.Context) (*Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.mu.Unlock()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, p.network, p.addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn)
+}
+
+func (p *Pool) put(conn *Conn) {
+	p.mu.Lock()
+	if !p.closed && len(p.idle) < p.MaxIdle {
+		p.idle = append(p.idle, conn)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	_ = conn.Close()
+}
+
+// Do runs fn on a pooled connection, reconnecting with exponential backoff while connection is broken.
+// Cancelling ctx interrupts the pending response and the connection is thrown away.
+func (p *Pool) Do(ctx context.Context, fn func(conn *Conn) error) error {
+	delay := p.Backoff
+	for attempt := 0; ; attempt++ {
+		err := p.do(ctx, fn)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !IsTemporary(err) || attempt >= p.Retries {
+			return err
+		}
+		p.Logger.Infof("reconnect to %s in %s: %v", p.addr, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+		if delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+func (p *Pool) do(ctx context.Context, fn func(conn *Conn) error) error {
+	conn, err := p.get(ctx)
+	if err != nil {
+		return err
+	}
+	// a past deadline is the only way to interrupt a pending response
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.NetConn().SetDeadline(time.Now())
+	})
+	err = fn(conn)
+	if !stop() || (err != nil && !isStatusError(err)) {
+		_ = conn.NetConn().Close()
+		return err
+	}
+	p.put(conn)
+	return err
+}
+
+// isStatusError tells if err is an answer of server like no match, which leaves connection usable
+func isStatusError(err error) bool {
+	var statusErr *textproto.Error
+	return errors.Is(err, ErrNoMatch) || errors.Is(err, ErrInvalidDatabase) ||
+		errors.Is(err, ErrInvalidStrategy) || errors.As(err, &statusErr)
+}
+
+// keepAlive sends STATUS on idle connections so that server does not drop them
+func (p *Pool) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		p.mu.Lock()
+		idle := p.idle
+		p.idle = nil
+		p.mu.Unlock()
+		for _, conn := range idle {
+			_ = conn.NetConn().SetDeadline(time.Now().Add(interval))
+			_, err := conn.Status()
+			_ = conn.NetConn().SetDeadline(time.Time{})
+			if err != nil {
+				p.Logger.Debugln("drop idle dict connection:", err)
+				_ = conn.NetConn().Close()
+				continue
+			}
+			p.put(conn)
+		}
+	}
+}
+
+// Close closes idle connections, connections in use are closed once returned
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+	close(p.stop)
+	var errs []error
+	for _, conn := range idle {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,416 @@
+package dictserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/dictproto"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultStrategy is used for MATCH with "." as strategy
+const DefaultStrategy = "lev"
+
+// titled is a dictionary with a description for SHOW DB
+type titled interface {
+	Title() string
+}
+
+// Server speaks DICT protocol (RFC 2229) and exposes every dictionary as a database by its name
+type Server struct {
+	Databases []dictionary.Interface
+	Logger    *log.Logger
+	// Timeout bounds each DEFINE or MATCH, zero means no limit
+	Timeout  time.Duration
+	hostname string
+	started  time.Time
+	requests atomic.Int64
+}
+
+func New(logger *log.Logger, databases []dictionary.Interface) *Server {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return &Server{
+		Databases: databases,
+		Logger:    logger,
+		hostname:  hostname,
+		started:   time.Now(),
+	}
+}
+
+// ListenAndServe serves on addr until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve accepts connections on l until ctx is done
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(ctx, conn)
+		}()
+	}
+}
+
+// session is the state of one client connection
+type session struct {
+	text *textproto.Conn
+	mime bool
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// unblock ReadLine once server is closing
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+	sess := &session{text: textproto.NewConn(conn)}
+	s.Logger.Infoln("dict client connected:", conn.RemoteAddr())
+	defer s.Logger.Infoln("dict client disconnected:", conn.RemoteAddr())
+	msgID := fmt.Sprintf("<%d.%d@%s>", os.Getpid(), time.Now().UnixNano(), s.hostname)
+	if err := sess.text.PrintfLine("220 %s tui-dictionary <mime> %s", s.hostname, msgID); err != nil {
+		return
+	}
+	for {
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		s.requests.Add(1)
+		args, err := dictproto.SplitLine(line)
+		if err != nil {
+			if err := sess.text.PrintfLine("501 syntax error, illegal parameters"); err != nil {
+				return
+			}
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit, err := s.dispatch(ctx, sess, args)
+		if err != nil {
+			s.Logger.Debugln("dict connection closed:", err)
+			return
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+func (s *Server) dispatch(ctx context.Context, sess *session, args []string) (bool, error) {
+	command := strings.ToUpper(args[0])
+	switch {
+	case command == "DEFINE" && len(args) == 3:
+		return false, s.define(ctx, sess, args[1], args[2])
+	case command == "MATCH" && len(args) == 4:
+		return false, s.match(ctx, sess, args[1], args[2], args[3])
+	case command == "SHOW" && len(args) >= 2:
+		return false, s.show(sess, args[1:])
+	case command == "CLIENT":
+		return false, sess.text.PrintfLine("250 ok")
+	case command == "OPTION" && len(args) == 2 && strings.EqualFold(args[1], "MIME"):
+		sess.mime = true
+		return false, sess.text.PrintfLine("250 ok - using MIME headers")
+	case command == "STATUS":
+		return false, sess.text.PrintfLine("210 status [d/m/c = %d/0/0; %.3fr]", s.requests.Load(), time.Since(s.started).Seconds())
+	case command == "HELP":
+		return false, s.help(sess)
+	case command == "QUIT":
+		return true, sess.text.PrintfLine("221 bye")
+	case command == "DEFINE", command == "MATCH", command == "SHOW", command == "OPTION":
+		return false, sess.text.PrintfLine("501 syntax error, illegal parameters")
+	default:
+		return false, sess.text.PrintfLine("500 unknown command")
+	}
+}
+
+// databases picks dictionaries by name, "*" and "!" mean all of them
+func (s *Server) databases(name string) ([]dictionary.Interface, bool) {
+	if name == "*" || name == "!" {
+		return s.Databases, true
+	}
+	for _, db := range s.Databases {
+		if db.GetName() == name {
+			return []dictionary.Interface{db}, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout > 0 {
+		return context.WithTimeout(ctx, s.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+type databaseDefinitions struct {
+	db          dictionary.Interface
+	definitions []entity.Definition
+}
+
+func (s *Server) define(ctx context.Context, sess *session, database, word string) error {
+	dbs, ok := s.databases(database)
+	if !ok {
+		return sess.text.PrintfLine("550 invalid database, use \"SHOW DB\" for list of databases")
+	}
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+	// web dictionaries are slow so all databases are searched at once
+	results := make([][]entity.Definition, len(dbs))
+	var wg sync.WaitGroup
+	for idx, db := range dbs {
+		idx, db := idx, db
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			definitions, err := db.Search(ctx, word)
+			if err != nil && !errors.Is(err, dictionary.ErrorNoDef) {
+				s.Logger.Warnln("dict define", db.GetName(), word, err)
+			}
+			results[idx] = definitions
+		}()
+	}
+	wg.Wait()
+	found := make([]databaseDefinitions, 0, len(dbs))
+	for idx, definitions := range results {
+		if len(definitions) == 0 {
+			continue
+		}
+		found = append(found, databaseDefinitions{db: dbs[idx], definitions: definitions})
+		// "!" stops at the first database having a definition
+		if database == "!" {
+			break
+		}
+	}
+	if len(found) == 0 {
+		return sess.text.PrintfLine("552 no match")
+	}
+	if err := sess.text.PrintfLine("150 %d definitions retrieved", len(found)); err != nil {
+		return err
+	}
+	for _, f := range found {
+		if err := sess.text.PrintfLine("151 %s %s %s", dictproto.Quote(word), f.db.GetName(), dictproto.Quote(description(f.db))); err != nil {
+			return err
+		}
+		if err := s.writeText(sess, formatDefinitions(word, f.definitions)); err != nil {
+			return err
+		}
+	}
+	return sess.text.PrintfLine("250 ok")
+}
+
+// formatDefinitions writes numbered senses with examples under the headword like dictd articles
+func formatDefinitions(word string, definitions []entity.Definition) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", word)
+	for idx, d := range definitions {
+		fmt.Fprintf(&b, "  %d. %s\n", idx+1, d.String())
+		for _, example := range d.Examples {
+			fmt.Fprintf(&b, "     \"%s\"\n", example)
+		}
+	}
+	return b.String()
+}
+
+func (s *Server) match(ctx context.Context, sess *session, database, strategy, word string) error {
+	dbs, ok := s.databases(database)
+	if !ok {
+		return sess.text.PrintfLine("550 invalid database, use \"SHOW DB\" for list of databases")
+	}
+	if strategy == "." {
+		strategy = DefaultStrategy
+	}
+	if _, ok := dictionary.MatchStrategies[strategy]; !ok {
+		return sess.text.PrintfLine("551 invalid strategy, use \"SHOW STRAT\" for a list of strategies")
+	}
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+	lines := make([]string, 0)
+	for _, db := range dbs {
+		matches, err := s.matchDatabase(ctx, db, strategy, word)
+		if err != nil {
+			s.Logger.Warnln("dict match", db.GetName(), word, err)
+			continue
+		}
+		for _, match := range matches {
+			lines = append(lines, fmt.Sprintf("%s %s", db.GetName(), dictproto.Quote(match)))
+		}
+		if database == "!" && len(matches) != 0 {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		return sess.text.PrintfLine("552 no match")
+	}
+	if err := sess.text.PrintfLine("152 %d matches found", len(lines)); err != nil {
+		return err
+	}
+	if err := s.writeLines(sess, lines); err != nil {
+		return err
+	}
+	return sess.text.PrintfLine("250 ok")
+}
+
+// matchDatabase asks Matcher if possible, other dictionaries can only tell whether word itself exists
+func (s *Server) matchDatabase(ctx context.Context, db dictionary.Interface, strategy, word string) ([]string, error) {
+	if matcher, ok := db.(dictionary.Matcher); ok {
+		return matcher.Match(ctx, strategy, word)
+	}
+	definitions, err := db.Search(ctx, word)
+	if errors.Is(err, dictionary.ErrorNoDef) || len(definitions) == 0 {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return []string{word}, nil
+}
+
+func (s *Server) show(sess *session, args []string) error {
+	switch strings.ToUpper(args[0]) {
+	case "DB", "DATABASES":
+		if len(s.Databases) == 0 {
+			return sess.text.PrintfLine("554 no databases present")
+		}
+		lines := make([]string, 0, len(s.Databases))
+		for _, db := range s.Databases {
+			lines = append(lines, fmt.Sprintf("%s %s", db.GetName(), dictproto.Quote(description(db))))
+		}
+		if err := sess.text.PrintfLine("110 %d databases present", len(lines)); err != nil {
+			return err
+		}
+		if err := s.writeLines(sess, lines); err != nil {
+			return err
+		}
+	case "STRAT", "STRATEGIES":
+		names := make([]string, 0, len(dictionary.MatchStrategies))
+		for name := range dictionary.MatchStrategies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines := make([]string, 0, len(names))
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("%s %s", name, dictproto.Quote(dictionary.MatchStrategies[name])))
+		}
+		if err := sess.text.PrintfLine("111 %d strategies available", len(lines)); err != nil {
+			return err
+		}
+		if err := s.writeLines(sess, lines); err != nil {
+			return err
+		}
+	case "INFO":
+		if len(args) != 2 {
+			return sess.text.PrintfLine("501 syntax error, illegal parameters")
+		}
+		dbs, ok := s.databases(args[1])
+		if !ok || len(dbs) != 1 {
+			return sess.text.PrintfLine("550 invalid database, use \"SHOW DB\" for list of databases")
+		}
+		if err := sess.text.PrintfLine("112 database information follows"); err != nil {
+			return err
+		}
+		info := fmt.Sprintf("%s\n%s\n", dbs[0].GetName(), description(dbs[0]))
+		if err := s.writeText(sess, info); err != nil {
+			return err
+		}
+	case "SERVER":
+		if err := sess.text.PrintfLine("114 server information follows"); err != nil {
+			return err
+		}
+		info := fmt.Sprintf("tui-dictionary on %s, up %s, %d databases\n", s.hostname, time.Since(s.started).Round(time.Second), len(s.Databases))
+		if err := s.writeText(sess, info); err != nil {
+			return err
+		}
+	default:
+		return sess.text.PrintfLine("501 syntax error, illegal parameters")
+	}
+	return sess.text.PrintfLine("250 ok")
+}
+
+func (s *Server) help(sess *session) error {
+	if err := sess.text.PrintfLine("113 help text follows"); err != nil {
+		return err
+	}
+	help := `DEFINE database word         -- look up word in database
+MATCH database strategy word -- match word in database using strategy
+SHOW DB                      -- list all accessible databases
+SHOW STRAT                   -- list available matching strategies
+SHOW INFO database           -- provide information about the database
+SHOW SERVER                  -- provide site-specific information
+OPTION MIME                  -- use MIME headers
+CLIENT info                  -- identify client to server
+STATUS                       -- display timing information
+HELP                         -- display this help information
+QUIT                         -- terminate connection
+`
+	if err := s.writeText(sess, help); err != nil {
+		return err
+	}
+	return sess.text.PrintfLine("250 ok")
+}
+
+// writeText sends a dot-terminated text block, led by a MIME header if client asked for it
+func (s *Server) writeText(sess *session, text string) error {
+	w := sess.text.DotWriter()
+	if sess.mime {
+		if _, err := w.Write([]byte("Content-type: text/plain; charset=utf-8\n\n")); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write([]byte(text)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (s *Server) writeLines(sess *session, lines []string) error {
+	w := sess.text.DotWriter()
+	for _, line := range lines {
+		if _, err := w.Write([]byte(line + "\n")); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+func description(db dictionary.Interface) string {
+	if t, ok := db.(titled); ok {
+		return t.Title()
+	}
+	return db.GetName()
+}
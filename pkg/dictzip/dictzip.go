@@ -0,0 +1,209 @@
+package dictzip
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// gzip header flags, see RFC 1952
+const (
+	flagHCRC    = 1 << 1
+	flagExtra   = 1 << 2
+	flagName    = 1 << 3
+	flagComment = 1 << 4
+)
+
+var ErrNotDictzip = errors.New("not a dictzip file")
+
+// Reader gives random access to a dictzip (.dz) file by decompressing only the chunks it needs,
+// a plain file is read as it is and a gzip file without chunk table is decompressed at once
+type Reader struct {
+	f *os.File
+	// chunkLength is the uncompressed size of each chunk
+	chunkLength int64
+	// chunkOffsets are the compressed offsets of chunks with the end of last chunk
+	chunkOffsets []int64
+	// content is set if the whole file is kept in memory
+	content []byte
+	plain   bool
+
+	mu          sync.Mutex
+	cachedIndex int
+	cachedChunk []byte
+}
+
+// Open opens path as dictzip, gzip or plain file by its header
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{f: f, cachedIndex: -1}
+	if err := r.readHeader(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reader) readHeader() error {
+	br := bufio.NewReader(r.f)
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(br, header); err != nil || header[0] != 0x1f || header[1] != 0x8b {
+		r.plain = true
+		return nil
+	}
+	flags := header[3]
+	offset := int64(len(header))
+	if flags&flagExtra != 0 {
+		var xlen uint16
+		if err := binary.Read(br, binary.LittleEndian, &xlen); err != nil {
+			return err
+		}
+		extra := make([]byte, xlen)
+		if _, err := io.ReadFull(br, extra); err != nil {
+			return err
+		}
+		offset += 2 + int64(xlen)
+		if err := r.parseExtra(extra); err != nil && !errors.Is(err, ErrNotDictzip) {
+			return err
+		}
+	}
+	for _, flag := range []byte{flagName, flagComment} {
+		if flags&flag == 0 {
+			continue
+		}
+		s, err := br.ReadBytes(0)
+		if err != nil {
+			return err
+		}
+		offset += int64(len(s))
+	}
+	if flags&flagHCRC != 0 {
+		offset += 2
+	}
+	if len(r.chunkOffsets) == 0 {
+		return r.readAll()
+	}
+	for idx := range r.chunkOffsets {
+		r.chunkOffsets[idx] += offset
+	}
+	return nil
+}
+
+// parseExtra reads the "RA" subfield: version, chunk length, chunk count and compressed size of each chunk
+func (r *Reader) parseExtra(extra []byte) error {
+	for len(extra) >= 4 {
+		id := string(extra[:2])
+		length := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+length {
+			return fmt.Errorf("corrupted gzip extra field")
+		}
+		data := extra[4 : 4+length]
+		extra = extra[4+length:]
+		if id != "RA" {
+			continue
+		}
+		if len(data) < 6 {
+			return fmt.Errorf("corrupted dictzip chunk table")
+		}
+		r.chunkLength = int64(binary.LittleEndian.Uint16(data[2:4]))
+		count := int(binary.LittleEndian.Uint16(data[4:6]))
+		if r.chunkLength == 0 || count == 0 {
+			return fmt.Errorf("corrupted dictzip chunk table: %d chunks of length %d", count, r.chunkLength)
+		}
+		if len(data) < 6+2*count {
+			return fmt.Errorf("corrupted dictzip chunk table")
+		}
+		r.chunkOffsets = make([]int64, count+1)
+		for idx := 0; idx < count; idx++ {
+			size := int64(binary.LittleEndian.Uint16(data[6+2*idx:]))
+			r.chunkOffsets[idx+1] = r.chunkOffsets[idx] + size
+		}
+		return nil
+	}
+	return ErrNotDictzip
+}
+
+// readAll keeps a gzip file without chunk table in memory
+func (r *Reader) readAll() error {
+	if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	gz, err := gzip.NewReader(r.f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	r.content, err = io.ReadAll(gz)
+	return err
+}
+
+func (r *Reader) chunk(idx int) ([]byte, error) {
+	if idx == r.cachedIndex {
+		return r.cachedChunk, nil
+	}
+	compressed := make([]byte, r.chunkOffsets[idx+1]-r.chunkOffsets[idx])
+	if _, err := r.f.ReadAt(compressed, r.chunkOffsets[idx]); err != nil {
+		return nil, err
+	}
+	fr := flate.NewReader(bytes.NewReader(compressed))
+	defer fr.Close()
+	// each chunk ends with a sync flush instead of a final block
+	data, err := io.ReadAll(fr)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	r.cachedIndex, r.cachedChunk = idx, data
+	return data, nil
+}
+
+// ReadAt implements io.ReaderAt on the uncompressed content
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if r.plain {
+		return r.f.ReadAt(p, off)
+	}
+	if r.content != nil {
+		if off >= int64(len(r.content)) {
+			return 0, io.EOF
+		}
+		n := copy(p, r.content[off:])
+		if n < len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		idx := int(pos / r.chunkLength)
+		if idx >= len(r.chunkOffsets)-1 {
+			return n, io.EOF
+		}
+		data, err := r.chunk(idx)
+		if err != nil {
+			return n, err
+		}
+		start := pos - int64(idx)*r.chunkLength
+		if start >= int64(len(data)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], data[start:])
+	}
+	return n, nil
+}
+
+func (r *Reader) Close() error {
+	return r.f.Close()
+}
@@ -1,19 +1,56 @@
 package entity
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
-type DictionaryLanguage int
+// DictionaryLanguage identifies a language registered in package language
+type DictionaryLanguage string
 type DictionaryType int
 
 const (
-	English DictionaryLanguage = iota
-	Russian
+	English DictionaryLanguage = "english"
+	Russian DictionaryLanguage = "russian"
 )
 
 const (
 	EnglishMyPrefer DictionaryType = iota
 	RussianMyPrefer
 	EnglishMyPreferWithUrban
+	EnglishMyPreferWithLocal
+	// LocalDictionary is a dictionary file on disk like StarDict or dictd
+	LocalDictionary
 )
 
 var ErrUnknownLanguage = errors.New("unknown language")
+
+// Definition is a single sense of a word found by a dictionary
+type Definition struct {
+	Text         string `json:"text"`
+	PartOfSpeech string `json:"part_of_speech,omitempty"`
+	// Source is the name of dictionary giving this definition
+	Source   string   `json:"source,omitempty"`
+	Examples []string `json:"examples,omitempty"`
+	// Sense is the 1-based order of this definition in its source
+	Sense  int      `json:"sense,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+	// MergedSources are other dictionaries giving the same definition
+	MergedSources []string `json:"merged_sources,omitempty"`
+	// Offline means it comes from saved lookups instead of the dictionary itself
+	Offline bool `json:"offline,omitempty"`
+}
+
+// String formats definition with part of speech and labels in one line
+func (d Definition) String() string {
+	var b strings.Builder
+	if len(d.PartOfSpeech) != 0 {
+		fmt.Fprintf(&b, "(%s) ", d.PartOfSpeech)
+	}
+	if len(d.Labels) != 0 {
+		fmt.Fprintf(&b, "[%s] ", strings.Join(d.Labels, ", "))
+	}
+	b.WriteString(d.Text)
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/tools"
+)
+
+// Entry is a single lookup
+type Entry struct {
+	Word       string                    `json:"word"`
+	Language   entity.DictionaryLanguage `json:"language"`
+	Sources    []string                  `json:"sources,omitempty"`
+	Saved      bool                      `json:"saved"`
+	SearchedAt time.Time                 `json:"searched_at"`
+}
+
+// History keeps lookups on disk as a single json file, oldest first
+type History struct {
+	path string
+	// MaxSize is the max number of entries, zero means no limit
+	MaxSize int
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// DefaultPath gives history path under user config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tui-dictionary", "history.json"), nil
+}
+
+// New loads history from path, a missing file is an empty history
+func New(path string, maxSize int) (*History, error) {
+	h := &History{
+		path:    path,
+		MaxSize: maxSize,
+		entries: make([]Entry, 0),
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Add appends a lookup and writes history back to disk
+func (h *History) Add(entry Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if entry.SearchedAt.IsZero() {
+		entry.SearchedAt = time.Now()
+	}
+	h.entries = append(h.entries, entry)
+	if h.MaxSize > 0 && len(h.entries) > h.MaxSize {
+		h.entries = h.entries[len(h.entries)-h.MaxSize:]
+	}
+	data, err := json.Marshal(h.entries)
+	if err != nil {
+		return err
+	}
+	return tools.WriteFileAtomic(h.path, data, 0600)
+}
+
+// Recent gives lookups in language newest first, the same word only once
+func (h *History) Recent(lang entity.DictionaryLanguage) []Entry {
+	return h.Filter(lang, "")
+}
+
+// Filter gives lookups in language whose word or sources contain query, newest first, the same word only once
+func (h *History) Filter(lang entity.DictionaryLanguage, query string) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	query = strings.ToLower(query)
+	seen := make(map[string]struct{})
+	entries := make([]Entry, 0)
+	for idx := len(h.entries) - 1; idx >= 0; idx-- {
+		entry := h.entries[idx]
+		word := strings.ToLower(entry.Word)
+		if entry.Language != lang {
+			continue
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		if len(query) != 0 && !strings.Contains(word, query) &&
+			!strings.Contains(strings.ToLower(strings.Join(entry.Sources, " ")), query) {
+			continue
+		}
+		seen[word] = struct{}{}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,216 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+	log "github.com/sirupsen/logrus"
+)
+
+// SourceResult is the answer of one dictionary in a define response
+type SourceResult struct {
+	Source      string              `json:"source"`
+	Definitions []entity.Definition `json:"definitions"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// DefineResponse is the body of GET /v1/define
+type DefineResponse struct {
+	Word    string         `json:"word"`
+	Source  string         `json:"source"`
+	Results []SourceResult `json:"results"`
+}
+
+var errMissingWord = errors.New("missing word")
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler serves lookups of dictionaries by their names as JSON
+type Handler struct {
+	Dictionaries []dictionary.Interface
+	// DefaultSources are the dictionary names used when source is not given, keyed by language
+	DefaultSources map[entity.DictionaryLanguage]string
+	// Timeout bounds each lookup, zero means no limit
+	Timeout time.Duration
+	Logger  *log.Logger
+	mux     *http.ServeMux
+}
+
+func New(logger *log.Logger, dictionaries []dictionary.Interface, defaultSources map[entity.DictionaryLanguage]string) *Handler {
+	h := &Handler{
+		Dictionaries:   dictionaries,
+		DefaultSources: defaultSources,
+		Logger:         logger,
+		mux:            http.NewServeMux(),
+	}
+	h.mux.HandleFunc("GET /v1/define", h.define)
+	h.mux.HandleFunc("GET /v1/sources", h.sources)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// parseLanguage is English if lang is not given
+func parseLanguage(lang string) (*language.Language, error) {
+	if len(lang) == 0 {
+		return language.English, nil
+	}
+	return language.Parse(lang)
+}
+
+func (h *Handler) lookupDictionary(name string) (dictionary.Interface, bool) {
+	for _, dict := range h.Dictionaries {
+		if dict.GetName() == name {
+			return dict, true
+		}
+	}
+	return nil, false
+}
+
+func (h *Handler) sources(w http.ResponseWriter, _ *http.Request) {
+	names := make([]string, 0, len(h.Dictionaries))
+	for _, dict := range h.Dictionaries {
+		names = append(names, dict.GetName())
+	}
+	h.writeJSON(w, http.StatusOK, map[string][]string{"sources": names})
+}
+
+func (h *Handler) define(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	word := strings.TrimSpace(query.Get("word"))
+	if len(word) == 0 {
+		h.writeError(w, http.StatusBadRequest, errMissingWord)
+		return
+	}
+	lang, err := parseLanguage(query.Get("lang"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := lang.Validate(word); err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	source := query.Get("source")
+	if len(source) == 0 {
+		source = h.DefaultSources[lang.ID]
+	}
+	dict, ok := h.lookupDictionary(source)
+	if !ok {
+		h.writeError(w, http.StatusNotFound, errors.New("unknown source: "+source))
+		return
+	}
+	ctx := r.Context()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	response := DefineResponse{Word: word, Source: source}
+	var searchErr error
+	if streamer, ok := dict.(dictionary.Streamer); ok {
+		response.Results, searchErr = collectStream(ctx, streamer, word)
+	} else {
+		definitions, err := dict.Search(ctx, word)
+		searchErr = err
+		if len(definitions) != 0 {
+			response.Results = []SourceResult{{Source: source, Definitions: definitions}}
+		}
+	}
+	if len(response.Results) != 0 {
+		h.writeJSON(w, http.StatusOK, response)
+		return
+	}
+	h.writeError(w, statusOf(searchErr), searchErr)
+}
+
+// collectStream keeps every dictionary's answer in configured order, failed ones carry their error.
+// The returned error explains why there is no definition at all.
+func collectStream(ctx context.Context, streamer dictionary.Streamer, word string) ([]SourceResult, error) {
+	received := make([]dictionary.SourceResult, 0, len(streamer.Sources()))
+	for res := range streamer.SearchStream(ctx, word) {
+		received = append(received, res)
+	}
+	found := false
+	var failure error
+	byIndex := make(map[int]SourceResult, len(received))
+	for _, res := range received {
+		result := SourceResult{Source: res.Source, Definitions: res.Definitions}
+		if res.Err != nil && !errors.Is(res.Err, dictionary.ErrorNoDef) {
+			sourceErr := &dictionary.SourceError{Source: res.Source, Err: res.Err}
+			result.Error = sourceErr.Error()
+			failure = sourceErr
+		}
+		found = found || len(res.Definitions) != 0
+		byIndex[res.Index] = result
+	}
+	if ctx.Err() != nil && !found {
+		return nil, ctx.Err()
+	}
+	if !found {
+		if failure == nil {
+			failure = dictionary.ErrorNoDef
+		}
+		return nil, failure
+	}
+	// arranged definitions keep dedupe and source order of streamer
+	arranged := streamer.Arrange(received)
+	results := make([]SourceResult, 0, len(byIndex))
+	for idx := 0; idx <= len(streamer.Sources()); idx++ {
+		result, ok := byIndex[idx]
+		if !ok {
+			continue
+		}
+		result.Definitions = make([]entity.Definition, 0)
+		for _, d := range arranged {
+			if d.Source == result.Source || (idx == len(streamer.Sources()) && d.Offline) {
+				result.Definitions = append(result.Definitions, d)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// statusOf maps search error to HTTP status
+func statusOf(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, dictionary.ErrorNoDef):
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		// client went away, nobody reads the status
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		h.Logger.Warnln("lookup failed:", err)
+	}
+	h.writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.Logger.Debugln("Fail to write response:", err)
+	}
+}
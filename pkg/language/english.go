@@ -0,0 +1,51 @@
+package language
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/aaaton/golem/v4/dicts/en"
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/morph"
+)
+
+var englishWordRe = regexp.MustCompile(`(?s)^[a-zA-Z\s]+$`)
+
+var English = &Language{
+	ID:          entity.English,
+	Code:        "en",
+	Placeholder: "test",
+	Validate: func(word string) error {
+		if !englishWordRe.MatchString(word) {
+			return ErrWord
+		}
+		return nil
+	},
+	Normalize:    strings.ToLower,
+	Preprocessor: morph.NewGolem(en.New()),
+	KeyboardRows: qwertyRows,
+	Dictionaries: []DictionaryChoice{
+		{
+			Display: "English to English",
+			Type:    entity.EnglishMyPrefer,
+			New:     dictionary.NewMyPreferDictionary,
+		},
+		{
+			Display: "English to English (w/Urban)",
+			Type:    entity.EnglishMyPreferWithUrban,
+			New:     dictionary.NewMyPreferWithUrbanDictionary,
+		},
+		{
+			Display:   "English to English (w/local dictionaries)",
+			Type:      entity.EnglishMyPreferWithLocal,
+			New:       dictionary.NewMyPreferDictionary,
+			WithLocal: true,
+		},
+	},
+	DefaultSource: "eng-plain-prefer",
+}
+
+func init() {
+	Register(English)
+}
@@ -0,0 +1,138 @@
+// Package language registers what the rest of the program needs to know about each supported language
+package language
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/morph"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrWord = errors.New("should be a word")
+
+// qwertyRows are the rows of QWERTY layout from top, shortcuts are defined on them
+var qwertyRows = []string{"qwertyuiop[]", "asdfghjkl;'", "zxcvbnm,."}
+
+// DictionaryChoice is a dictionary combination offered for a language
+type DictionaryChoice struct {
+	Display string
+	Type    entity.DictionaryType
+	New     func(logger *log.Logger) (*dictionary.MyPrefer, error)
+	// WithLocal adds local dictionaries of the language, the choice is hidden without them
+	WithLocal bool
+}
+
+// PreprocessorOptions are what NewPreprocessor of a language may use
+type PreprocessorOptions struct {
+	// MorphPath is the morphology dictionary on disk
+	MorphPath string
+	// Offline leaves out preprocessors which need network
+	Offline bool
+}
+
+// Language is a registered language
+type Language struct {
+	ID entity.DictionaryLanguage
+	// Code is ISO 639-1 code
+	Code string
+	// Placeholder is the example word in search input
+	Placeholder string
+	// Validate tells if input is made of letters of the language
+	Validate func(word string) error
+	// Normalize cleans up a word before lookup in word lists
+	Normalize func(word string) string
+	// Preprocessor gives base forms of a typed word, nil means searching the word as typed
+	Preprocessor morph.Preprocessor
+	// NewPreprocessor replaces Preprocessor by one using local data, nil means Preprocessor is used as is
+	NewPreprocessor func(logger *log.Logger, opts PreprocessorOptions) morph.Preprocessor
+	// KeyboardRows are the rows of keyboard layout from top, aligned with QWERTY keys
+	KeyboardRows []string
+	// Dictionaries are offered in the order given
+	Dictionaries []DictionaryChoice
+	// DefaultSource is the dictionary name searched by serve commands if none is asked
+	DefaultSource string
+}
+
+var registry = make([]*Language, 0)
+
+// Register adds a language, the UI lists languages in the order registered
+func Register(lang *Language) {
+	registry = append(registry, lang)
+}
+
+// All gives registered languages
+func All() []*Language {
+	return registry
+}
+
+// Get finds a language by its id
+func Get(id entity.DictionaryLanguage) (*Language, error) {
+	for _, lang := range registry {
+		if lang.ID == id {
+			return lang, nil
+		}
+	}
+	return nil, entity.ErrUnknownLanguage
+}
+
+// Parse finds a language by its id or code
+func Parse(s string) (*Language, error) {
+	s = strings.ToLower(s)
+	for _, lang := range registry {
+		if string(lang.ID) == s || lang.Code == s {
+			return lang, nil
+		}
+	}
+	return nil, entity.ErrUnknownLanguage
+}
+
+// Detect finds the language validating word, the first registered one wins
+func Detect(word string) (*Language, error) {
+	for _, lang := range registry {
+		if lang.Validate(word) == nil {
+			return lang, nil
+		}
+	}
+	return nil, entity.ErrUnknownLanguage
+}
+
+// NormalizeWord cleans up word by the normalizer of language if any
+func (l *Language) NormalizeWord(word string) string {
+	word = strings.TrimSpace(word)
+	if l.Normalize == nil {
+		return word
+	}
+	return l.Normalize(word)
+}
+
+// ShortcutKey maps a key typed in any registered layout to the QWERTY key at the same place,
+// so that shortcuts work without switching keyboard layout
+func ShortcutKey(key string) string {
+	runes := []rune(key)
+	if len(runes) != 1 {
+		return key
+	}
+	for _, lang := range registry {
+		for row, keys := range lang.KeyboardRows {
+			if row >= len(qwertyRows) {
+				break
+			}
+			qwerty := []rune(qwertyRows[row])
+			for idx, r := range []rune(keys) {
+				if idx >= len(qwerty) {
+					break
+				}
+				switch runes[0] {
+				case r:
+					return string(qwerty[idx])
+				case []rune(strings.ToUpper(string(r)))[0]:
+					return strings.ToUpper(string(qwerty[idx]))
+				}
+			}
+		}
+	}
+	return key
+}
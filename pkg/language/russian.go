@@ -0,0 +1,73 @@
+package language
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/morph"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrCyrillic = errors.New("should be a cyrillic")
+
+// combiningAcute marks stress in Russian words
+const combiningAcute = '́'
+
+var Russian = &Language{
+	ID:          entity.Russian,
+	Code:        "ru",
+	Placeholder: "о́пыт",
+	Validate: func(word string) error {
+		for _, c := range word {
+			if !unicode.Is(unicode.Cyrillic, c) && c != combiningAcute && !unicode.IsSpace(c) {
+				return ErrCyrillic
+			}
+		}
+		return nil
+	},
+	Normalize: func(word string) string {
+		return strings.ToLower(strings.ReplaceAll(word, string(combiningAcute), ""))
+	},
+	Preprocessor:    morph.RussianOnline,
+	NewPreprocessor: russianPreprocessor,
+	KeyboardRows:    []string{"йцукенгшщзхъ", "фывапролджэ", "ячсмитьбю"},
+	Dictionaries: []DictionaryChoice{
+		{
+			Display: "Russian to English",
+			Type:    entity.RussianMyPrefer,
+			New:     dictionary.NewMyPreferRUDictionary,
+		},
+	},
+	DefaultSource: "ru-prefer",
+}
+
+// russianPreprocessor prefers morphology dictionary on disk and falls back to online preprocessing
+func russianPreprocessor(logger *log.Logger, opts PreprocessorOptions) morph.Preprocessor {
+	preprocessors := make(morph.Chain, 0, 2)
+	if _, err := os.Stat(opts.MorphPath); err == nil {
+		offline := morph.NewRussianFile(opts.MorphPath)
+		// the dictionary is large so it is loaded in background like lexicon, the first search waits for it
+		go func() {
+			if err := offline.Load(); err != nil {
+				logger.Warnln("Fail to load Russian morphology:", err)
+				return
+			}
+			logger.Infoln("loaded Russian morphology with", offline.Len(), "lemmas from", opts.MorphPath)
+		}()
+		preprocessors = append(preprocessors, offline)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logger.Warnln("Fail to load Russian morphology:", err)
+	}
+	if !opts.Offline {
+		preprocessors = append(preprocessors, morph.RussianOnline)
+	}
+	return preprocessors
+}
+
+func init() {
+	Register(Russian)
+}
@@ -0,0 +1,45 @@
+package lexicon
+
+import (
+	"sort"
+	"strings"
+)
+
+// Complete gives at most limit known words starting with prefix,
+// saved words come first and shorter words before longer ones
+func (l *Lexicon) Complete(prefix string, limit int) []string {
+	prefix = l.normalize(prefix)
+	if len(prefix) == 0 || limit <= 0 {
+		return nil
+	}
+	l.mu.RLock()
+	saved := make([]string, 0)
+	others := make([]string, 0)
+	for idx := sort.SearchStrings(l.words, prefix); idx < len(l.words); idx++ {
+		word := l.words[idx]
+		if !strings.HasPrefix(word, prefix) {
+			break
+		}
+		if word == prefix {
+			continue
+		}
+		if _, ok := l.saved[word]; ok {
+			saved = append(saved, word)
+		} else {
+			others = append(others, word)
+		}
+	}
+	l.mu.RUnlock()
+	byLength := func(words []string) {
+		sort.SliceStable(words, func(i, j int) bool {
+			return len(words[i]) < len(words[j])
+		})
+	}
+	byLength(saved)
+	byLength(others)
+	completions := append(saved, others...)
+	if len(completions) > limit {
+		completions = completions[:limit]
+	}
+	return completions
+}
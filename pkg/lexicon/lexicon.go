@@ -0,0 +1,146 @@
+// Package lexicon keeps known words of a language for spelling suggestions and completion
+package lexicon
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/s8508235/tui-dictionary/pkg/language"
+	"github.com/s8508235/tui-dictionary/pkg/morph"
+)
+
+// Lexicon is a set of words in one language, it is safe for concurrent use
+type Lexicon struct {
+	Language *language.Language
+	mu       sync.RWMutex
+	words    []string // sorted and normalized
+	known    map[string]struct{}
+	// saved are words written to target files, they come first in completion
+	saved map[string]struct{}
+}
+
+func New(lang *language.Language) *Lexicon {
+	return &Lexicon{
+		Language: lang,
+		known:    make(map[string]struct{}),
+		saved:    make(map[string]struct{}),
+	}
+}
+
+// normalize cleans up word by the lexicon language
+func (l *Lexicon) normalize(word string) string {
+	return l.Language.NormalizeWord(word)
+}
+
+// Add keeps words of the lexicon language, others are ignored
+func (l *Lexicon) Add(words ...string) {
+	l.add(false, words...)
+}
+
+// AddSaved keeps words written to target file
+func (l *Lexicon) AddSaved(words ...string) {
+	l.add(true, words...)
+}
+
+func (l *Lexicon) add(saved bool, words ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	added := false
+	for _, word := range words {
+		word = l.normalize(word)
+		if len(word) == 0 || l.Language.Validate(word) != nil {
+			continue
+		}
+		if saved {
+			l.saved[word] = struct{}{}
+		}
+		if _, ok := l.known[word]; ok {
+			continue
+		}
+		l.known[word] = struct{}{}
+		l.words = append(l.words, word)
+		added = true
+	}
+	if added {
+		sort.Strings(l.words)
+	}
+}
+
+// Len is the number of words
+func (l *Lexicon) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.words)
+}
+
+// Contains tells if word is known
+func (l *Lexicon) Contains(word string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.known[l.normalize(word)]
+	return ok
+}
+
+// LoadWordList reads one word per line, lines of target files like "word\tdefinitions" give their first column
+func (l *Lexicon) LoadWordList(r io.Reader) error {
+	return l.load(r, false)
+}
+
+func (l *Lexicon) load(r io.Reader, saved bool) error {
+	words := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		word, _, _ := strings.Cut(scanner.Text(), "\t")
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	l.add(saved, words...)
+	return nil
+}
+
+// LoadFiles reads word lists, missing files are skipped
+func (l *Lexicon) LoadFiles(paths ...string) error {
+	return l.loadFiles(false, paths...)
+}
+
+// LoadTargets reads saved words of target files, missing files are skipped
+func (l *Lexicon) LoadTargets(paths ...string) error {
+	return l.loadFiles(true, paths...)
+}
+
+func (l *Lexicon) loadFiles(saved bool, paths ...string) error {
+	for _, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		err = l.load(f, saved)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadWordSource reads every word form known by source
+func (l *Lexicon) LoadWordSource(source morph.WordSource) error {
+	r, err := source.Words()
+	if err != nil {
+		return err
+	}
+	return l.LoadWordList(r)
+}
@@ -0,0 +1,184 @@
+package lexicon
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/s8508235/tui-dictionary/pkg/language"
+)
+
+const (
+	insertCost     = 1.0
+	substituteCost = 1.0
+	// typing the key next to the intended one is the most common typo
+	adjacentCost  = 0.5
+	transposeCost = 0.75
+	// ё is often typed as е
+	similarCost = 0.25
+)
+
+// adjacentKeys are neighbours on keyboard layouts of registered languages,
+// each row is shifted half a key to the right of the row above
+var adjacentKeys = buildAdjacentKeys()
+
+func buildAdjacentKeys() map[rune]map[rune]struct{} {
+	adjacent := make(map[rune]map[rune]struct{})
+	link := func(a, b rune) {
+		if adjacent[a] == nil {
+			adjacent[a] = make(map[rune]struct{})
+		}
+		if adjacent[b] == nil {
+			adjacent[b] = make(map[rune]struct{})
+		}
+		adjacent[a][b] = struct{}{}
+		adjacent[b][a] = struct{}{}
+	}
+	for _, lang := range language.All() {
+		layout := lang.KeyboardRows
+		for r, row := range layout {
+			keys := []rune(row)
+			for i, key := range keys {
+				if i+1 < len(keys) {
+					link(key, keys[i+1])
+				}
+				if r+1 < len(layout) {
+					below := []rune(layout[r+1])
+					for _, j := range []int{i - 1, i} {
+						if j >= 0 && j < len(below) {
+							link(key, below[j])
+						}
+					}
+				}
+			}
+		}
+	}
+	link('е', 'ё')
+	return adjacent
+}
+
+func substitution(a, b rune) float64 {
+	switch {
+	case a == b:
+		return 0
+	case (a == 'е' && b == 'ё') || (a == 'ё' && b == 'е'):
+		return similarCost
+	}
+	if _, ok := adjacentKeys[a][b]; ok {
+		return adjacentCost
+	}
+	return substituteCost
+}
+
+// Distance is the optimal string alignment distance of a and b,
+// substitution of keys next to each other and transposition cost less than other edits
+func Distance(a, b string) float64 {
+	var buf distanceBuffer
+	return buf.within([]rune(a), []rune(b), -1)
+}
+
+// distanceBuffer keeps rows between calls to avoid allocation when scanning many words
+type distanceBuffer struct {
+	rows [3][]float64
+}
+
+// within gives up once distance surely exceeds max, negative max means no limit
+func (buf *distanceBuffer) within(ra, rb []rune, max float64) float64 {
+	// the last three rows are enough for transposition
+	for idx := range buf.rows {
+		if cap(buf.rows[idx]) < len(rb)+1 {
+			buf.rows[idx] = make([]float64, len(rb)+1)
+		}
+		buf.rows[idx] = buf.rows[idx][:len(rb)+1]
+	}
+	prev2, prev, curr := buf.rows[0], buf.rows[1], buf.rows[2]
+	for j := range prev {
+		prev[j] = float64(j) * insertCost
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = float64(i) * insertCost
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			best := prev[j-1] + substitution(ra[i-1], rb[j-1])
+			if d := prev[j] + insertCost; d < best {
+				best = d
+			}
+			if d := curr[j-1] + insertCost; d < best {
+				best = d
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if d := prev2[j-2] + transposeCost; d < best {
+					best = d
+				}
+			}
+			curr[j] = best
+			if best < rowMin {
+				rowMin = best
+			}
+		}
+		if max >= 0 && rowMin > max {
+			return rowMin
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+type scored struct {
+	word     string
+	distance float64
+}
+
+// Suggest ranks at most limit known words close to word by Distance, word itself is never suggested
+func (l *Lexicon) Suggest(word string, limit int) []string {
+	word = l.normalize(word)
+	target := []rune(word)
+	if len(target) == 0 || limit <= 0 {
+		return nil
+	}
+	maxDistance := 2.0
+	if len(target) <= 4 {
+		maxDistance = 1
+	}
+	var buf distanceBuffer
+	candidate := make([]rune, 0, len(target)+int(maxDistance))
+	l.mu.RLock()
+	candidates := make([]scored, 0)
+	for _, known := range l.words {
+		if known == word {
+			continue
+		}
+		diff := utf8.RuneCountInString(known) - len(target)
+		if float64(diff) > maxDistance || float64(-diff) > maxDistance {
+			continue
+		}
+		candidate = candidate[:0]
+		for _, r := range known {
+			candidate = append(candidate, r)
+		}
+		if d := buf.within(target, candidate, maxDistance); d <= maxDistance {
+			candidates = append(candidates, scored{word: known, distance: d})
+		}
+	}
+	l.mu.RUnlock()
+	first := target[0]
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		// people rarely mistype the first letter
+		iFirst := []rune(candidates[i].word)[0] == first
+		jFirst := []rune(candidates[j].word)[0] == first
+		if iFirst != jFirst {
+			return iFirst
+		}
+		return candidates[i].word < candidates[j].word
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	suggestions := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		suggestions = append(suggestions, c.word)
+	}
+	return suggestions
+}
@@ -0,0 +1,66 @@
+package morph
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aaaton/golem/v4"
+)
+
+// Golem lemmatizes by a golem language pack, which is loaded on first use
+type Golem struct {
+	Pack       golem.LanguagePack
+	once       sync.Once
+	lemmatizer *golem.Lemmatizer
+	err        error
+	// mu guards Lemmas of golem which sorts shared slices
+	mu sync.Mutex
+}
+
+func NewGolem(pack golem.LanguagePack) *Golem {
+	return &Golem{Pack: pack}
+}
+
+// Load reads language pack, it can be called ahead to avoid delay of the first search
+func (g *Golem) Load() error {
+	g.once.Do(func() {
+		g.lemmatizer, g.err = golem.New(g.Pack)
+	})
+	return g.err
+}
+
+// Words gives every word form of language pack
+func (g *Golem) Words() (io.Reader, error) {
+	resource, err := g.Pack.GetResource()
+	if err != nil {
+		return nil, err
+	}
+	// each line is a lemma followed by its forms, all separated by tabs
+	return bytes.NewReader(bytes.ReplaceAll(resource, []byte("\t"), []byte("\n"))), nil
+}
+
+// Lemmas gives every base form of word, base forms other than word itself come first.
+// Lemma of golem is not used since Lemmas sorts the forms in place and changes what Lemma gives later.
+func (g *Golem) Lemmas(_ context.Context, word string) ([]string, error) {
+	if err := g.Load(); err != nil {
+		return nil, err
+	}
+	lower := strings.ToLower(word)
+	candidates := make([]string, 0)
+	g.mu.Lock()
+	forms := g.lemmatizer.Lemmas(word)
+	for _, form := range forms {
+		if !containsString(candidates, form) {
+			candidates = append(candidates, form)
+		}
+	}
+	g.mu.Unlock()
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i] != lower && candidates[j] == lower
+	})
+	return candidates, nil
+}
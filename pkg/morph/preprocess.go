@@ -0,0 +1,68 @@
+// Package morph turns typed words into the base forms searched in dictionaries
+package morph
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/s8508235/tui-dictionary/pkg/tools"
+)
+
+var ErrUnknownWord = errors.New("unknown word")
+
+// Preprocessor gives the base forms of word, the most likely one first
+type Preprocessor interface {
+	Lemmas(ctx context.Context, word string) ([]string, error)
+}
+
+// WordSource is a Preprocessor that knows every word form of its language
+type WordSource interface {
+	Preprocessor
+	// Load reads the forms ahead so the first search does not wait for it
+	Load() error
+	// Words gives the forms, one per line
+	Words() (io.Reader, error)
+}
+
+// Func adapts a function giving a single base form to Preprocessor, an empty one means the word is unknown
+type Func func(ctx context.Context, word string) (string, error)
+
+func (f Func) Lemmas(ctx context.Context, word string) ([]string, error) {
+	lemma, err := f(ctx, word)
+	if err != nil {
+		return nil, err
+	}
+	if lemma = strings.TrimSpace(lemma); len(lemma) == 0 {
+		return nil, ErrUnknownWord
+	}
+	return []string{lemma}, nil
+}
+
+// RussianOnline annotates stress by russiangram.com, it needs network
+var RussianOnline = Func(tools.RussianPreprocess)
+
+// Chain asks preprocessors in order until one of them knows the word
+type Chain []Preprocessor
+
+func (c Chain) Lemmas(ctx context.Context, word string) ([]string, error) {
+	errs := make([]error, 0, len(c))
+	for _, p := range c {
+		lemmas, err := p.Lemmas(ctx, word)
+		if err == nil && len(lemmas) != 0 {
+			return lemmas, nil
+		}
+		if err == nil {
+			err = ErrUnknownWord
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, ErrUnknownWord
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,205 @@
+package morph
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// stressMark is combining acute accent put after stressed vowel
+const stressMark = '\u0301'
+
+// Russian lemmatizes Russian words and adds stress marks without network.
+// It is built from a morphology dictionary where each paradigm is a block of lines separated by a blank line
+// and the first line of a block is the base form, like OpenCorpora dict.opcorpora.txt
+// ("ЁЖ\tNOUN,anim,masc sing,nomn") or Zaliznyak based lists ("абажу'р | сущ ед муж неод им").
+// Stress is marked by U+0301, an apostrophe or a backtick after the stressed vowel.
+type Russian struct {
+	lemmas []string
+	// forms are folded word forms to the indexes of their lemmas
+	forms map[string][]int32
+}
+
+// LoadRussian reads a morphology dictionary file, compressed by gzip or bzip2 if it ends with .gz or .bz2
+func LoadRussian(path string) (*Russian, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch filepath.Ext(path) {
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	case ".bz2":
+		r = bzip2.NewReader(f)
+	}
+	return NewRussian(r)
+}
+
+// RussianFile is Russian morphology from a dictionary file, which is loaded on first use
+type RussianFile struct {
+	Path string
+	once sync.Once
+	dict *Russian
+	err  error
+}
+
+func NewRussianFile(path string) *RussianFile {
+	return &RussianFile{Path: path}
+}
+
+// Load reads dictionary file, it can be called ahead to avoid delay of the first search
+func (r *RussianFile) Load() error {
+	r.once.Do(func() {
+		r.dict, r.err = LoadRussian(r.Path)
+	})
+	return r.err
+}
+
+// Len is the number of base forms, zero before loaded
+func (r *RussianFile) Len() int {
+	if r.Load() != nil {
+		return 0
+	}
+	return r.dict.Len()
+}
+
+func (r *RussianFile) Lemmas(ctx context.Context, word string) ([]string, error) {
+	if err := r.Load(); err != nil {
+		return nil, err
+	}
+	return r.dict.Lemmas(ctx, word)
+}
+
+// NewRussian reads a morphology dictionary
+func NewRussian(r io.Reader) (*Russian, error) {
+	dict := &Russian{
+		lemmas: make([]string, 0),
+		forms:  make(map[string][]int32),
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lemma := int32(-1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			// next paradigm
+			lemma = -1
+			continue
+		}
+		if isNumber(line) {
+			// OpenCorpora paradigm id
+			continue
+		}
+		form := line
+		if idx := strings.IndexAny(line, "\t|"); idx >= 0 {
+			form = strings.TrimSpace(line[:idx])
+		}
+		form = markStress(strings.ToLower(form))
+		if len(form) == 0 {
+			continue
+		}
+		if lemma == -1 {
+			lemma = int32(len(dict.lemmas))
+			dict.lemmas = append(dict.lemmas, form)
+		}
+		folded := fold(form)
+		indexes := dict.forms[folded]
+		if len(indexes) != 0 && indexes[len(indexes)-1] == lemma {
+			continue
+		}
+		dict.forms[folded] = append(indexes, lemma)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return dict, nil
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// markStress turns apostrophe or backtick after a vowel into U+0301
+func markStress(form string) string {
+	runes := []rune(form)
+	out := make([]rune, 0, len(runes))
+	for idx, r := range runes {
+		if (r == '\'' || r == '`') && idx > 0 && isVowel(runes[idx-1]) {
+			out = append(out, stressMark)
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("аеёиоуыэюя", unicode.ToLower(r))
+}
+
+// fold is the lookup key of word ignoring case, stress and ё
+func fold(word string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case stressMark, '\'', '`':
+			return -1
+		case 'ё', 'Ё':
+			return 'е'
+		}
+		return unicode.ToLower(r)
+	}, strings.TrimSpace(word))
+}
+
+// Len is the number of base forms
+func (d *Russian) Len() int {
+	return len(d.lemmas)
+}
+
+// Lemmas gives stressed base forms of word, the word itself first if it is a base form
+func (d *Russian) Lemmas(_ context.Context, word string) ([]string, error) {
+	folded := fold(word)
+	indexes, ok := d.forms[folded]
+	if !ok {
+		return nil, ErrUnknownWord
+	}
+	lemmas := make([]string, 0, len(indexes))
+	for _, idx := range indexes {
+		lemma := d.lemmas[idx]
+		if !containsString(lemmas, lemma) {
+			lemmas = append(lemmas, lemma)
+		}
+	}
+	sort.SliceStable(lemmas, func(i, j int) bool {
+		return fold(lemmas[i]) == folded && fold(lemmas[j]) != folded
+	})
+	return lemmas, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+)
+
+// ankiTag is added to every note to find notes imported from here
+const ankiTag = "tui-dictionary"
+
+func openAnkiCSV(f *os.File) (*fileWriter, error) {
+	return openAnki(f, ',', "Comma")
+}
+
+func openAnkiTSV(f *os.File) (*fileWriter, error) {
+	return openAnki(f, '\t', "Tab")
+}
+
+// openAnki writes file headers of Anki text import to a new target,
+// an existing one must declare the same separator if it has headers.
+// See https://docs.ankiweb.net/importing/text-files.html#file-headers
+func openAnki(f *os.File, comma rune, separator string) (*fileWriter, error) {
+	empty, err := padLines(f)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		header := fmt.Sprintf("#separator:%s\n#html:true\n#columns:Front%sBack%sTags\n#tags column:3\n",
+			separator, string(comma), string(comma))
+		if _, err := f.WriteString(header); err != nil {
+			return nil, err
+		}
+	} else if err := checkAnkiHeader(f, separator); err != nil {
+		return nil, err
+	}
+	return &fileWriter{f: f, encode: func(entry Entry) ([]byte, error) {
+		return encodeAnki(entry, comma)
+	}}, nil
+}
+
+func checkAnkiHeader(f *os.File, separator string) error {
+	scanner := bufio.NewScanner(io.NewSectionReader(f, 0, 1<<16))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "#separator:"); ok && !strings.EqualFold(value, separator) {
+			return fmt.Errorf("%w: separator is %s instead of %s", ErrCorrupt, value, separator)
+		}
+	}
+	return scanner.Err()
+}
+
+// AnkiNote gives word on front, numbered definitions with examples on back as HTML,
+// and tags of language, source dictionaries and the entry itself
+func AnkiNote(entry Entry) (front, back string, tags []string) {
+	var b strings.Builder
+	b.WriteString("<ol>")
+	tags = []string{ankiTag}
+	if len(entry.Language) != 0 {
+		tags = append(tags, string(entry.Language))
+	}
+	for _, d := range entry.Definitions {
+		b.WriteString("<li>")
+		b.WriteString(html.EscapeString(d.String()))
+		for _, example := range d.Examples {
+			b.WriteString(ankiExample)
+			b.WriteString(html.EscapeString(example))
+			b.WriteString("</i>")
+		}
+		b.WriteString("</li>")
+		if len(d.Source) != 0 {
+			tag := strings.Join(strings.Fields(d.Source), "_")
+			if !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	b.WriteString("</ol>")
+	for _, tag := range entry.Tags {
+		if !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return entry.Word, b.String(), tags
+}
+
+const ankiExample = "<br><i>"
+
+func encodeAnki(entry Entry, comma rune) ([]byte, error) {
+	front, back, tags := AnkiNote(entry)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = comma
+	if err := w.Write([]string{front, back, strings.Join(tags, " ")}); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func indexAnkiCSV(data []byte) ([]located, error) {
+	return indexAnki(data, ',')
+}
+
+func indexAnkiTSV(data []byte) ([]located, error) {
+	return indexAnki(data, '\t')
+}
+
+// indexAnki takes definitions from back written by AnkiNote, other back is a single definition
+func indexAnki(data []byte, comma rune) ([]located, error) {
+	lines := lineSpans(data)
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	entries := make([]located, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCorrupt, err)
+		}
+		word := strings.TrimSpace(record[0])
+		if len(word) == 0 {
+			continue
+		}
+		entry := Entry{Word: word, Definitions: make([]entity.Definition, 0)}
+		if len(record) > 1 {
+			entry.Definitions = parseAnkiBack(record[1])
+		}
+		if len(record) > 2 {
+			entry.Tags = strings.Fields(record[2])
+			for _, tag := range entry.Tags {
+				if lang, err := language.Get(entity.DictionaryLanguage(tag)); err == nil {
+					entry.Language = lang.ID
+				}
+			}
+		}
+		// a record starts at the line of its first field, comments before it are kept
+		line, _ := reader.FieldPos(0)
+		entries = append(entries, located{Entry: entry, start: lines[line-1][0], end: int(reader.InputOffset())})
+	}
+}
+
+func parseAnkiBack(back string) []entity.Definition {
+	definitions := make([]entity.Definition, 0)
+	items, ok := strings.CutPrefix(back, "<ol><li>")
+	if !ok {
+		if back = strings.TrimSpace(back); len(back) != 0 {
+			definitions = append(definitions, entity.Definition{Text: html.UnescapeString(back), Sense: 1})
+		}
+		return definitions
+	}
+	items = strings.TrimSuffix(items, "</li></ol>")
+	for _, item := range strings.Split(items, "</li><li>") {
+		parts := strings.Split(item, ankiExample)
+		d := entity.Definition{Text: html.UnescapeString(parts[0]), Sense: len(definitions) + 1}
+		for _, example := range parts[1:] {
+			d.Examples = append(d.Examples, html.UnescapeString(strings.TrimSuffix(example, "</i>")))
+		}
+		definitions = append(definitions, d)
+	}
+	return definitions
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Owned tells if target at path is written by this program in format, the file is left unchanged.
+// Every entry must have a definition, so notes or changelogs sharing an extension are not taken.
+func Owned(path string, format Format) (bool, error) {
+	spec, err := lookup(format)
+	if err != nil {
+		return false, err
+	}
+	if spec.ownedPath != nil {
+		return spec.ownedPath(path)
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return false, err
+	}
+	if spec.sniff != nil && !spec.sniff(data) {
+		return false, nil
+	}
+	located, err := spec.index(data)
+	if errors.Is(err, ErrCorrupt) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	for _, l := range located {
+		if len(l.Definitions) == 0 {
+			return false, nil
+		}
+	}
+	return len(located) != 0, nil
+}
+
+// Targets gives files in dir written by this program in the format of their extensions
+func Targets(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]string, 0)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		format, err := FormatOf(file.Name())
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		if owned, err := Owned(path, format); err != nil {
+			return nil, err
+		} else if owned {
+			targets = append(targets, path)
+		}
+	}
+	return targets, nil
+}
+
+// sniffQuizlet takes content whose every line is "word\tdefinitions"
+func sniffQuizlet(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		word, definitions, found := strings.Cut(line, "\t")
+		if !found || len(strings.TrimSpace(word)) == 0 || len(strings.TrimSpace(definitions)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sniffAnki takes content starting with the file headers written by openAnki
+func sniffAnki(data []byte) bool {
+	return strings.HasPrefix(string(data), "#separator:")
+}
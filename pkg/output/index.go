@@ -0,0 +1,64 @@
+package output
+
+import (
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
+
+// Index finds saved entries of a word in target, it is not safe for concurrent use
+type Index struct {
+	entries map[string][]Entry
+}
+
+func NewIndex(entries []Entry) *Index {
+	i := &Index{entries: make(map[string][]Entry)}
+	for _, entry := range entries {
+		i.Add(entry)
+	}
+	return i
+}
+
+// Get gives saved entries of the same word, in the order saved
+func (i *Index) Get(word string) []Entry {
+	return i.entries[WordKey(word)]
+}
+
+// Add keeps entry after saved entries of the same word
+func (i *Index) Add(entry Entry) {
+	key := WordKey(entry.Word)
+	i.entries[key] = append(i.entries[key], entry)
+}
+
+// Set keeps entry instead of saved entries of the same word
+func (i *Index) Set(entry Entry) {
+	i.entries[WordKey(entry.Word)] = []Entry{entry}
+}
+
+// Len is the number of saved words
+func (i *Index) Len() int {
+	return len(i.entries)
+}
+
+// MergeDefinitions gives saved definitions followed by new ones not saved yet,
+// definitions are the same if they are written the same in one line
+func MergeDefinitions(saved []Entry, definitions []entity.Definition) []entity.Definition {
+	merged := make([]entity.Definition, 0, len(definitions))
+	seen := make(map[string]struct{})
+	add := func(d entity.Definition) {
+		key := FormatDefinition(d)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		d.Sense = len(merged) + 1
+		merged = append(merged, d)
+	}
+	for _, entry := range saved {
+		for _, d := range entry.Definitions {
+			add(d)
+		}
+	}
+	for _, d := range definitions {
+		add(d)
+	}
+	return merged
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// maxLine is the longest last line checked for a broken entry
+const maxLine = 1 << 20
+
+// openJSONLines refuses a target whose last line is broken json, which is left by an interrupted write
+func openJSONLines(f *os.File) (*fileWriter, error) {
+	last, err := tail(f, maxLine)
+	if err != nil {
+		return nil, err
+	}
+	if len(last) != 0 && last[len(last)-1] != '\n' {
+		line := last[bytes.LastIndexByte(last, '\n')+1:]
+		if !json.Valid(line) {
+			return nil, ErrCorrupt
+		}
+		if _, err := f.WriteString("\n"); err != nil {
+			return nil, err
+		}
+	}
+	return &fileWriter{f: f, encode: encodeJSONLines}, nil
+}
+
+func encodeJSONLines(entry Entry) ([]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func indexJSONLines(data []byte) ([]located, error) {
+	entries := make([]located, 0)
+	for _, span := range lineSpans(data) {
+		line := bytes.TrimSpace(data[span[0]:span[1]])
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCorrupt, err)
+		}
+		entries = append(entries, located{Entry: entry, start: span[0], end: span[1]})
+	}
+	return entries, nil
+}
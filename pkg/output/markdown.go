@@ -0,0 +1,81 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
+
+// openMarkdown keeps a blank line between existing content and the next entry
+func openMarkdown(f *os.File) (*fileWriter, error) {
+	last, err := tail(f, 2)
+	if err != nil {
+		return nil, err
+	}
+	var pad string
+	switch {
+	case len(last) == 0, string(last) == "\n\n":
+	case last[len(last)-1] == '\n':
+		pad = "\n"
+	default:
+		pad = "\n\n"
+	}
+	if _, err := f.WriteString(pad); err != nil {
+		return nil, err
+	}
+	return &fileWriter{f: f, encode: encodeMarkdown}, nil
+}
+
+// encodeMarkdown gives a section of word with numbered definitions and their examples
+func encodeMarkdown(entry Entry) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", entry.Word)
+	for idx, d := range entry.Definitions {
+		fmt.Fprintf(&b, "%d. %s", idx+1, d.String())
+		if len(d.Source) != 0 {
+			fmt.Fprintf(&b, " _(%s)_", d.Source)
+		}
+		b.WriteString("\n")
+		for _, example := range d.Examples {
+			fmt.Fprintf(&b, "   - %s\n", example)
+		}
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
+
+var markdownDefinitionRe = regexp.MustCompile(`^\d+\. (.*?)(?: _\((.+)\)_)?$`)
+
+// indexMarkdown takes sections written by encodeMarkdown, a section lasts until the next one
+// and other lines are ignored
+func indexMarkdown(data []byte) ([]located, error) {
+	entries := make([]located, 0)
+	for _, span := range lineSpans(data) {
+		line := strings.TrimRight(string(data[span[0]:span[1]]), "\r\n")
+		if word, ok := strings.CutPrefix(line, "## "); ok {
+			entries = append(entries, located{
+				Entry: Entry{Word: strings.TrimSpace(word), Definitions: make([]entity.Definition, 0)},
+				start: span[0],
+			})
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		entry := &entries[len(entries)-1]
+		entry.end = span[1]
+		if example, ok := strings.CutPrefix(line, "   - "); ok && len(entry.Definitions) != 0 {
+			last := &entry.Definitions[len(entry.Definitions)-1]
+			last.Examples = append(last.Examples, example)
+		} else if match := markdownDefinitionRe.FindStringSubmatch(line); match != nil {
+			entry.Definitions = append(entry.Definitions, entity.Definition{
+				Text:   match[1],
+				Source: match[2],
+				Sense:  len(entry.Definitions) + 1,
+			})
+		}
+	}
+	return entries, nil
+}
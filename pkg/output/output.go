@@ -0,0 +1,382 @@
+// Package output writes saved words to target files in formats importable by flashcard apps
+package output
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/tools"
+)
+
+// Format is a target file format
+type Format string
+
+const (
+	// Quizlet is "word\tdef1;def2" per line, the original target format
+	Quizlet   Format = "quizlet"
+	AnkiCSV   Format = "anki-csv"
+	AnkiTSV   Format = "anki-tsv"
+	JSONLines Format = "jsonl"
+	Markdown  Format = "markdown"
+	// SQLite keeps every saved definition with its source, language, timestamps and lookup counts
+	SQLite Format = "sqlite"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown output format")
+	// ErrCorrupt means existing target can not be appended safely
+	ErrCorrupt = errors.New("file corrupt")
+)
+
+// Entry is a word saved with its chosen definitions
+type Entry struct {
+	Word        string                    `json:"word"`
+	Language    entity.DictionaryLanguage `json:"language,omitempty"`
+	Definitions []entity.Definition       `json:"definitions"`
+	SavedAt     time.Time                 `json:"saved_at"`
+	// Tags are read from Anki targets, where they are the only record of source dictionaries,
+	// and written back with the tags of definitions
+	Tags []string `json:"-"`
+}
+
+// Writer appends entries to a target it owns until closed
+type Writer interface {
+	Write(entry Entry) error
+	// Replace removes saved entries of the same word and writes entry instead
+	Replace(entry Entry) error
+	// Rewrite makes entries in order the only entries of target
+	Rewrite(entries []Entry) error
+	// Entries gives every entry saved in target in order
+	Entries() ([]Entry, error)
+	Close() error
+}
+
+// located is an entry with its byte range in target
+type located struct {
+	Entry
+	start, end int
+}
+
+type formatSpec struct {
+	format    Format
+	extension string
+	// open checks and pads existing content of f, empty f is a new target
+	open func(f *os.File) (*fileWriter, error)
+	// index finds every entry in content of target
+	index func(data []byte) ([]located, error)
+	// sniff tells if content is written by this program, nil means every content index takes
+	sniff func(data []byte) bool
+	// openPath, readPath and ownedPath are for targets not appended as plain files,
+	// which take place of open, index and sniff
+	openPath  func(path string) (Writer, error)
+	readPath  func(path string) ([]Entry, error)
+	ownedPath func(path string) (bool, error)
+}
+
+// formats are in the order of help message, extension of the first one is the default
+var formats = []formatSpec{
+	{format: Quizlet, extension: ".txt", open: openQuizlet, index: indexQuizlet, sniff: sniffQuizlet},
+	{format: AnkiCSV, extension: ".csv", open: openAnkiCSV, index: indexAnkiCSV, sniff: sniffAnki},
+	{format: AnkiTSV, extension: ".tsv", open: openAnkiTSV, index: indexAnkiTSV, sniff: sniffAnki},
+	{format: JSONLines, extension: ".jsonl", open: openJSONLines, index: indexJSONLines},
+	{format: Markdown, extension: ".md", open: openMarkdown, index: indexMarkdown},
+	{format: SQLite, extension: ".db", openPath: openSQLite, readPath: readSQLite, ownedPath: ownedSQLite},
+}
+
+func lookup(format Format) (formatSpec, error) {
+	for _, spec := range formats {
+		if spec.format == format {
+			return spec, nil
+		}
+	}
+	return formatSpec{}, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+// Formats gives every supported format
+func Formats() []Format {
+	result := make([]Format, 0, len(formats))
+	for _, spec := range formats {
+		result = append(result, spec.format)
+	}
+	return result
+}
+
+// Extensions gives file extensions of every supported format
+func Extensions() []string {
+	result := make([]string, 0, len(formats))
+	for _, spec := range formats {
+		result = append(result, spec.extension)
+	}
+	return result
+}
+
+// ParseFormat accepts a format name
+func ParseFormat(s string) (Format, error) {
+	spec, err := lookup(Format(strings.ToLower(s)))
+	if err != nil {
+		return "", err
+	}
+	return spec.format, nil
+}
+
+// Extension is the file extension of format with leading dot
+func Extension(format Format) string {
+	spec, err := lookup(format)
+	if err != nil {
+		return ""
+	}
+	return spec.extension
+}
+
+// FormatOf guesses format from extension of path
+func FormatOf(path string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, spec := range formats {
+		if spec.extension == ext {
+			return spec.format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, ext)
+}
+
+// Open opens path for appending in format, the returned writer closes the file.
+// os.DevNull gives a writer dropping everything.
+func Open(path string, format Format) (Writer, error) {
+	if path == os.DevNull {
+		return Discard, nil
+	}
+	spec, err := lookup(format)
+	if err != nil {
+		return nil, err
+	}
+	if spec.openPath != nil {
+		return spec.openPath(path)
+	}
+	f, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_RDWR|os.O_APPEND|os.O_SYNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w, err := spec.open(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	w.spec = spec
+	return w, nil
+}
+
+// Read gives every entry saved in path of format, fields a format does not keep are left empty
+func Read(path string, format Format) ([]Entry, error) {
+	spec, err := lookup(format)
+	if err != nil {
+		return nil, err
+	}
+	if spec.readPath != nil {
+		// a missing store is not created by reading
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		return spec.readPath(path)
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	located, err := spec.index(data)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(located))
+	for _, l := range located {
+		entries = append(entries, l.Entry)
+	}
+	return entries, nil
+}
+
+// Discard drops every entry
+var Discard Writer = discard{}
+
+type discard struct{}
+
+func (discard) Write(Entry) error         { return nil }
+func (discard) Replace(Entry) error       { return nil }
+func (discard) Rewrite([]Entry) error     { return nil }
+func (discard) Entries() ([]Entry, error) { return nil, nil }
+func (discard) Close() error              { return nil }
+
+// tail gives the last n bytes of f, fewer if f is shorter
+func tail(f *os.File, n int64) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < n {
+		n = size
+	}
+	buf := make([]byte, n)
+	read, err := f.ReadAt(buf, size-n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if int64(read) != n {
+		return nil, ErrCorrupt
+	}
+	return buf, nil
+}
+
+// padLines makes non-empty f end with a line break so the next entry starts on its own line
+func padLines(f *os.File) (empty bool, err error) {
+	last, err := tail(f, 1)
+	if err != nil {
+		return false, err
+	}
+	if len(last) == 0 {
+		return true, nil
+	}
+	if last[0] != '\n' {
+		if _, err := f.WriteString("\n"); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// fileWriter writes each entry with a single write so a crash never leaves half an entry
+type fileWriter struct {
+	f      *os.File
+	spec   formatSpec
+	encode func(entry Entry) ([]byte, error)
+}
+
+func (w *fileWriter) Write(entry Entry) error {
+	data, err := w.encode(entry)
+	if err != nil {
+		return err
+	}
+	_, err = w.f.Write(data)
+	return err
+}
+
+// Replace rewrites target without saved entries of the word at once, then appends entry.
+// Content between entries, like headers, is kept.
+func (w *fileWriter) Replace(entry Entry) error {
+	err := w.rewrite(func(data []byte, located []located) ([]byte, error) {
+		kept := make([]byte, 0, len(data))
+		last := 0
+		for _, l := range located {
+			if SameWord(l.Word, entry.Word) {
+				kept = append(kept, data[last:l.start]...)
+				last = l.end
+			}
+		}
+		return append(kept, data[last:]...), nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Write(entry)
+}
+
+// Rewrite writes entries in order instead of every saved entry at once,
+// content before the first saved entry, like headers, is kept
+func (w *fileWriter) Rewrite(entries []Entry) error {
+	return w.rewrite(func(data []byte, located []located) ([]byte, error) {
+		head := data
+		if len(located) != 0 {
+			head = data[:located[0].start]
+		}
+		content := append(make([]byte, 0, len(data)), head...)
+		for _, entry := range entries {
+			encoded, err := w.encode(entry)
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, encoded...)
+		}
+		return content, nil
+	})
+}
+
+// rewrite replaces target with the content given by edit from saved content at once and appends to it after
+func (w *fileWriter) rewrite(edit func(data []byte, located []located) ([]byte, error)) error {
+	path := w.f.Name()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	located, err := w.spec.index(data)
+	if err != nil {
+		return err
+	}
+	content, err := edit(data, located)
+	if err != nil {
+		return err
+	}
+	if err := tools.WriteFileAtomic(path, content, 0600); err != nil {
+		return err
+	}
+	// the descriptor still refers to the file renamed over
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	next, err := Open(path, w.spec.format)
+	if err != nil {
+		return err
+	}
+	*w = *next.(*fileWriter)
+	return nil
+}
+
+// Entries gives every entry saved in target in order
+func (w *fileWriter) Entries() ([]Entry, error) {
+	return Read(w.f.Name(), w.spec.format)
+}
+
+func (w *fileWriter) Close() error {
+	return w.f.Close()
+}
+
+// SameWord compares saved words ignoring case, surrounding spaces and stress marks
+func SameWord(a, b string) bool {
+	return WordKey(a) == WordKey(b)
+}
+
+// WordKey is what saved words are matched by in Index, Store and Anki notes
+func WordKey(word string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(word), stressMark, ""))
+}
+
+// stressMark is combining acute accent put after stressed vowel of Russian words
+const stressMark = "\u0301"
+
+// AppendsDuplicates tells if writing a saved word again keeps another entry of it,
+// Store merges definitions into the saved one instead
+func AppendsDuplicates(w Writer) bool {
+	_, ok := w.(*Store)
+	return !ok
+}
+
+// lineSpans gives byte range of every line including its line break
+func lineSpans(data []byte) [][2]int {
+	spans := make([][2]int, 0)
+	start := 0
+	for start < len(data) {
+		end := bytes.IndexByte(data[start:], '\n')
+		if end < 0 {
+			end = len(data)
+		} else {
+			end += start + 1
+		}
+		spans = append(spans, [2]int{start, end})
+		start = end
+	}
+	return spans
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+)
+
+// FormatDefinition flattens definition and its examples for one line output
+func FormatDefinition(d entity.Definition) string {
+	s := d.String()
+	if len(d.Examples) != 0 {
+		s += fmt.Sprintf(" e.g. %s", strings.Join(d.Examples, " / "))
+	}
+	return s
+}
+
+// openQuizlet pads existing target with a blank line like Quizlet export does
+func openQuizlet(f *os.File) (*fileWriter, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > 1 {
+		lastByte, err := tail(f, 2)
+		if err != nil {
+			return nil, err
+		}
+		if string(lastByte) != "\n\n" {
+			if _, err := f.WriteString("\n\n"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &fileWriter{f: f, encode: encodeQuizlet}, nil
+}
+
+func encodeQuizlet(entry Entry) ([]byte, error) {
+	definition := make([]string, 0, len(entry.Definitions))
+	for _, d := range entry.Definitions {
+		definition = append(definition, FormatDefinition(d))
+	}
+	return []byte(entry.Word + "\t" + strings.Join(definition, ";") + "\n"), nil
+}
+
+func indexQuizlet(data []byte) ([]located, error) {
+	entries := make([]located, 0)
+	for _, span := range lineSpans(data) {
+		line := strings.TrimRight(string(data[span[0]:span[1]]), "\r\n")
+		word, definitions, found := strings.Cut(line, "\t")
+		word = strings.TrimSpace(word)
+		if !found || len(word) == 0 {
+			continue
+		}
+		entry := Entry{Word: word, Definitions: make([]entity.Definition, 0)}
+		for _, definition := range strings.Split(definitions, ";") {
+			if definition = strings.TrimSpace(definition); len(definition) != 0 {
+				entry.Definitions = append(entry.Definitions, entity.Definition{Text: definition, Sense: len(entry.Definitions) + 1})
+			}
+		}
+		entries = append(entries, located{Entry: entry, start: span[0], end: span[1]})
+	}
+	return entries, nil
+}
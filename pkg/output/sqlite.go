@@ -0,0 +1,374 @@
+package output
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"modernc.org/sqlite"
+)
+
+// ErrNewerSchema means the store is written by a newer version of this program
+var ErrNewerSchema = errors.New("store schema is newer than supported")
+
+// ErrForeignStore means a database is not a store written by this program
+var ErrForeignStore = errors.New("not a store of tui-dictionary")
+
+// migrations upgrade store schema in order, the schema version kept in user_version is the number applied.
+// Never change an applied migration, append a new one instead.
+var migrations = []string{
+	`CREATE TABLE words (
+		id INTEGER PRIMARY KEY,
+		word TEXT NOT NULL,
+		language TEXT NOT NULL DEFAULT '',
+		lookup_count INTEGER NOT NULL DEFAULT 0,
+		save_count INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		UNIQUE (word, language)
+	);
+	CREATE TABLE definitions (
+		id INTEGER PRIMARY KEY,
+		word_id INTEGER NOT NULL REFERENCES words (id) ON DELETE CASCADE,
+		text TEXT NOT NULL,
+		part_of_speech TEXT NOT NULL DEFAULT '',
+		source TEXT NOT NULL DEFAULT '',
+		sense INTEGER NOT NULL DEFAULT 0,
+		labels TEXT NOT NULL DEFAULT '[]',
+		examples TEXT NOT NULL DEFAULT '[]',
+		saved_at TIMESTAMP NOT NULL,
+		UNIQUE (word_id, text, source)
+	);
+	CREATE INDEX ix_definitions_word_id ON definitions (word_id);`,
+	// words differing only in case are the same word, the earliest one keeps counts and definitions of others
+	`UPDATE words SET
+		lookup_count = (SELECT sum(w.lookup_count) FROM words w WHERE w.word = words.word COLLATE NOCASE AND w.language = words.language),
+		save_count = (SELECT sum(w.save_count) FROM words w WHERE w.word = words.word COLLATE NOCASE AND w.language = words.language)
+		WHERE id IN (SELECT min(id) FROM words GROUP BY word COLLATE NOCASE, language);
+	UPDATE OR IGNORE definitions SET word_id = (
+		SELECT min(w.id) FROM words w JOIN words d ON w.word = d.word COLLATE NOCASE AND w.language = d.language
+		WHERE d.id = definitions.word_id);
+	DELETE FROM words WHERE id NOT IN (SELECT min(id) FROM words GROUP BY word COLLATE NOCASE, language);
+	CREATE UNIQUE INDEX ux_words_word_nocase ON words (word COLLATE NOCASE, language);`,
+	// position orders words rewritten in order, words saved later without it come after
+	`ALTER TABLE words ADD COLUMN position INTEGER;`,
+	// word_key is the word without case and stress marks, which NOCASE of version 2 misses for non-ASCII letters,
+	// words with the same key are merged the same way
+	`ALTER TABLE words ADD COLUMN word_key TEXT NOT NULL DEFAULT '';
+	UPDATE words SET word_key = word_key_of(word);
+	UPDATE words SET
+		lookup_count = (SELECT sum(w.lookup_count) FROM words w WHERE w.word_key = words.word_key AND w.language = words.language),
+		save_count = (SELECT sum(w.save_count) FROM words w WHERE w.word_key = words.word_key AND w.language = words.language)
+		WHERE id IN (SELECT min(id) FROM words GROUP BY word_key, language);
+	UPDATE OR IGNORE definitions SET word_id = (
+		SELECT min(w.id) FROM words w JOIN words d ON w.word_key = d.word_key AND w.language = d.language
+		WHERE d.id = definitions.word_id);
+	DELETE FROM words WHERE id NOT IN (SELECT min(id) FROM words GROUP BY word_key, language);
+	DROP INDEX ux_words_word_nocase;
+	CREATE UNIQUE INDEX ux_words_word_key ON words (word_key, language);`,
+}
+
+func init() {
+	// migrations key stored words by the same WordKey as new ones
+	sqlite.MustRegisterDeterministicScalarFunction("word_key_of", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		word, _ := args[0].(string)
+		return WordKey(word), nil
+	})
+}
+
+// LookupRecorder is a target counting lookups of words besides saved ones
+type LookupRecorder interface {
+	RecordLookup(word string, lang entity.DictionaryLanguage) error
+}
+
+// Store keeps saved words in SQLite, the same definition of a word is saved once
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore opens or creates SQLite store at path and migrates it to the latest schema
+func OpenStore(path string) (*Store, error) {
+	dsn := (&url.URL{
+		Scheme:   "file",
+		Opaque:   filepath.ToSlash(filepath.Clean(path)),
+		RawQuery: "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite",
+	}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) migrate() error {
+	ctx := context.Background()
+	// a single connection makes user_version and migrations in the same transaction
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("%w: version %d", ErrNewerSchema, version)
+	}
+	for idx := version; idx < len(migrations); idx++ {
+		if _, err := tx.Exec(migrations[idx]); err != nil {
+			return fmt.Errorf("migrate to version %d: %w", idx+1, err)
+		}
+	}
+	if version == len(migrations) {
+		return nil
+	}
+	// pragma does not take parameters
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(migrations))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// upsertWord gives id of word, it is created if not stored yet
+func upsertWord(tx *sql.Tx, word string, lang entity.DictionaryLanguage, lookups, saves int, at time.Time) (int64, error) {
+	var id int64
+	err := tx.QueryRow(`INSERT INTO words (word, word_key, language, lookup_count, save_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (word_key, language) DO UPDATE SET
+			lookup_count = lookup_count + excluded.lookup_count,
+			save_count = save_count + excluded.save_count,
+			updated_at = excluded.updated_at
+		RETURNING id`, word, WordKey(word), string(lang), lookups, saves, at.UTC(), at.UTC()).Scan(&id)
+	return id, err
+}
+
+// Write stores word and definitions not stored yet
+func (s *Store) Write(entry Entry) error {
+	return s.write(entry, false)
+}
+
+// Replace drops stored definitions of the word before storing entry, counts of the word are kept
+func (s *Store) Replace(entry Entry) error {
+	return s.write(entry, true)
+}
+
+func (s *Store) write(entry Entry, replace bool) error {
+	savedAt := entry.SavedAt
+	if savedAt.IsZero() {
+		savedAt = time.Now()
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	wordID, err := upsertWord(tx, entry.Word, entry.Language, 0, 1, savedAt)
+	if err != nil {
+		return err
+	}
+	if replace {
+		if _, err := tx.Exec(`DELETE FROM definitions WHERE word_id = ?`, wordID); err != nil {
+			return err
+		}
+	}
+	if err := insertDefinitions(tx, wordID, entry.Definitions, savedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertDefinitions(tx *sql.Tx, wordID int64, definitions []entity.Definition, savedAt time.Time) error {
+	for _, d := range definitions {
+		labels, err := json.Marshal(nonNil(d.Labels))
+		if err != nil {
+			return err
+		}
+		examples, err := json.Marshal(nonNil(d.Examples))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO definitions (word_id, text, part_of_speech, source, sense, labels, examples, saved_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (word_id, text, source) DO NOTHING`,
+			wordID, d.Text, d.PartOfSpeech, d.Source, d.Sense, string(labels), string(examples), savedAt.UTC()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewrite makes entries in order the only saved words, counts of words are kept
+func (s *Store) Rewrite(entries []Entry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err := tx.Exec(`DELETE FROM definitions`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE words SET position = NULL`); err != nil {
+		return err
+	}
+	for idx, entry := range entries {
+		savedAt := entry.SavedAt
+		if savedAt.IsZero() {
+			savedAt = time.Now()
+		}
+		wordID, err := upsertWord(tx, entry.Word, entry.Language, 0, 0, savedAt)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE words SET position = ? WHERE id = ?`, idx, wordID); err != nil {
+			return err
+		}
+		if err := insertDefinitions(tx, wordID, entry.Definitions, savedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordLookup counts a search of word, it is stored without definitions if never saved
+func (s *Store) RecordLookup(word string, lang entity.DictionaryLanguage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err := upsertWord(tx, word, lang, 1, 0, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Entries gives saved words in the order first stored, words only looked up are left out
+func (s *Store) Entries() ([]Entry, error) {
+	rows, err := s.db.Query(`SELECT w.id, w.word, w.language, d.text, d.part_of_speech, d.source, d.sense, d.labels, d.examples, d.saved_at
+		FROM words w JOIN definitions d ON d.word_id = w.id
+		ORDER BY w.position IS NULL, w.position, w.created_at, w.id, d.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := make([]Entry, 0)
+	lastID := int64(-1)
+	for rows.Next() {
+		var (
+			id               int64
+			word, lang       string
+			d                entity.Definition
+			labels, examples string
+			savedAt          time.Time
+		)
+		if err := rows.Scan(&id, &word, &lang, &d.Text, &d.PartOfSpeech, &d.Source, &d.Sense, &labels, &examples, &savedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(labels), &d.Labels); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(examples), &d.Examples); err != nil {
+			return nil, err
+		}
+		if id != lastID {
+			entries = append(entries, Entry{Word: word, Language: entity.DictionaryLanguage(lang), Definitions: make([]entity.Definition, 0)})
+			lastID = id
+		}
+		last := &entries[len(entries)-1]
+		last.Definitions = append(last.Definitions, d)
+		if savedAt.After(last.SavedAt) {
+			last.SavedAt = savedAt
+		}
+	}
+	return entries, rows.Err()
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func openSQLite(path string) (Writer, error) {
+	return OpenStore(path)
+}
+
+// sqliteMagic starts every SQLite database file
+const sqliteMagic = "SQLite format 3\x00"
+
+// ownedSQLite takes a database with tables of Store, it is opened read only so nothing is migrated
+func ownedSQLite(path string) (bool, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return false, err
+	}
+	header := make([]byte, len(sqliteMagic))
+	_, err = io.ReadFull(f, header)
+	f.Close()
+	if err != nil || string(header) != sqliteMagic {
+		return false, nil
+	}
+	dsn := (&url.URL{Scheme: "file", Opaque: filepath.ToSlash(filepath.Clean(path)), RawQuery: "mode=ro"}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	var version, tables int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return false, nil
+	}
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name IN ('words', 'definitions')`).Scan(&tables); err != nil {
+		return false, nil
+	}
+	return version != 0 && tables == 2, nil
+}
+
+// readSQLite leaves databases of other programs untouched instead of migrating them
+func readSQLite(path string) ([]Entry, error) {
+	owned, err := ownedSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, ErrForeignStore
+	}
+	s, err := OpenStore(path)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.Entries()
+}
@@ -1,7 +1,7 @@
 package tools
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/extensions"
@@ -10,8 +10,9 @@ import (
 // preprocess -> strip accent -> write back
 // RussianPreprocess relies on https://russiangram.com/ to get vocabulary with the accent mark, pick always first one
 // despite changing the accents can not only complicate the communication, but change the meaning of a word completely.
-func RussianPreprocess(word string) (string, error) {
+func RussianPreprocess(ctx context.Context, word string) (string, error) {
 	c := colly.NewCollector()
+	c.Context = ctx
 	extensions.RandomUserAgent(c)
 	var viewState string
 	var viewStateGenerator string
@@ -42,9 +43,6 @@ func RussianPreprocess(word string) (string, error) {
 		eventValidation = e.Attr("value")
 		// fmt.Println("view state:", eventValidation)
 	})
-	c.OnError(func(_ *colly.Response, err error) {
-		fmt.Println("Something went wrong:", err)
-	})
 	err := c.Visit("https://russiangram.com/")
 	if err != nil {
 		return "", err
@@ -52,9 +50,6 @@ func RussianPreprocess(word string) (string, error) {
 	c.OnHTML("textarea.input-textbox", func(e *colly.HTMLElement) {
 		result = e.Text
 	})
-	c.OnError(func(_ *colly.Response, err error) {
-		fmt.Println("Something went wrong:", err)
-	})
 	var formData = map[string]string{
 		`__VIEWSTATE`:                           viewState,
 		`__VIEWSTATEGENERATOR`:                  viewStateGenerator,
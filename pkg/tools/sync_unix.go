@@ -0,0 +1,18 @@
+//go:build linux || unix
+
+package tools
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// syncDir flushes directory entries so a renamed file survives a crash
+func syncDir(dir string) error {
+	d, err := os.Open(filepath.Clean(dir))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
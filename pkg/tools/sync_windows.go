@@ -0,0 +1,8 @@
+//go:build windows
+
+package tools
+
+// syncDir does nothing since Windows can not open a directory to flush it, a rename is flushed with the file
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/s8508235/tui-dictionary/pkg/cache"
+	"github.com/s8508235/tui-dictionary/pkg/dictionary"
+	"github.com/s8508235/tui-dictionary/pkg/dictserver"
+	"github.com/s8508235/tui-dictionary/pkg/entity"
+	"github.com/s8508235/tui-dictionary/pkg/httpapi"
+	"github.com/s8508235/tui-dictionary/pkg/language"
+	"github.com/sirupsen/logrus"
+)
+
+// serverOptions are flags of serve commands
+type serverOptions struct {
+	useCache      bool
+	cachePath     string
+	cacheTTL      time.Duration
+	cacheSize     int
+	starDictDir   string
+	dictdDir      string
+	sourceTimeout time.Duration
+	searchTimeout time.Duration
+	addr          string
+}
+
+// runServer serves all dictionaries for serve-dict or serve-http until interrupted,
+// lookup cache is written before it returns
+func runServer(logger *logrus.Logger, command string, opts serverOptions) error {
+	var lookupCache *cache.Cache
+	if opts.useCache {
+		var err error
+		if lookupCache, err = cache.New(opts.cachePath, opts.cacheTTL, opts.cacheSize); err != nil {
+			return fmt.Errorf("fail to load cache: %w", err)
+		}
+		defer closeCache(logger, lookupCache)
+	}
+	locals, err := loadLocalDictionaries(logger, opts.starDictDir, opts.dictdDir)
+	if err != nil {
+		return fmt.Errorf("fail to find local dictionaries: %w", err)
+	}
+	databases, err := serverDictionaries(logger, locals, lookupCache, opts.sourceTimeout)
+	if err != nil {
+		return fmt.Errorf("fail to init dictionary: %w", err)
+	}
+	if command == "serve-http" {
+		if err := serveHTTP(logger, opts.addr, databases, opts.searchTimeout); err != nil {
+			return fmt.Errorf("fail to serve HTTP: %w", err)
+		}
+		return nil
+	}
+	if err := serveDict(logger, opts.addr, databases, opts.searchTimeout); err != nil {
+		return fmt.Errorf("fail to serve DICT: %w", err)
+	}
+	return nil
+}
+
+// serverDictionaries gives every web, aggregated and local dictionary for serve commands,
+// web dictionaries are behind lookupCache if it is not nil
+func serverDictionaries(logger *logrus.Logger, locals []localDictionary, lookupCache *cache.Cache, sourceTimeout time.Duration) ([]dictionary.Interface, error) {
+	databases, err := dictionary.NewAllDictionaries(logger)
+	if err != nil {
+		return nil, err
+	}
+	for idx, db := range databases {
+		if prefer, ok := db.(*dictionary.MyPrefer); ok {
+			prefer.Timeout = sourceTimeout
+			if lookupCache != nil {
+				prefer.UseCache(logger, lookupCache)
+			}
+		} else if lookupCache != nil {
+			databases[idx] = dictionary.NewCachedDictionary(logger, db, lookupCache)
+		}
+	}
+	for _, local := range locals {
+		databases = append(databases, local)
+	}
+	return databases, nil
+}
+
+// serveDict exposes dictionaries over DICT protocol until interrupted
+func serveDict(logger *logrus.Logger, addr string, databases []dictionary.Interface, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := dictserver.New(logger, databases)
+	server.Timeout = timeout
+	logger.Infoln("serving DICT on", addr)
+	return server.ListenAndServe(ctx, addr)
+}
+
+// serveHTTP exposes dictionaries as JSON API until interrupted
+func serveHTTP(logger *logrus.Logger, addr string, databases []dictionary.Interface, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defaultSources := make(map[entity.DictionaryLanguage]string)
+	for _, lang := range language.All() {
+		defaultSources[lang.ID] = lang.DefaultSource
+	}
+	handler := httpapi.New(logger, databases, defaultSources)
+	handler.Timeout = timeout
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second, //nolint:gomnd
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second) //nolint:gomnd
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Warnln("Fail to shutdown HTTP server:", err)
+		}
+	}()
+	logger.Infoln("serving HTTP on", addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}